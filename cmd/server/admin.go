@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+
+	"todo-api/internal/http/router"
+)
+
+// adminRouter returns the router of the admin listener with the debug
+// endpoints for api. Endpoints that change the service or expose its
+// internals are added to it rather than to api.
+func adminRouter(api *router.Router) *router.Router {
+	mux := &router.Router{}
+	mux.Handle(http.MethodGet, "/debug/routes", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, api.Routes())
+	}))
+	mux.Mount("/debug/pprof", pprofHandler())
+	return mux
+}
+
+// adminServer serves mux on its own listener, which is meant to be reachable
+// by operators only.
+func adminServer(addr string, mux *router.Router) *http.Server {
+	if err := mux.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      2 * time.Minute, // CPU profiles take 30s by default
+	}
+}
+
+// pprofHandler serves the profiles of net/http/pprof below any prefix, the
+// index links to them relative to /debug/pprof/.
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/profile", pprof.Profile)
+	mux.HandleFunc("/symbol", pprof.Symbol)
+	mux.HandleFunc("/trace", pprof.Trace)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if name := strings.TrimPrefix(r.URL.Path, "/"); name != "" {
+			pprof.Handler(name).ServeHTTP(w, r)
+			return
+		}
+		pprof.Index(w, r)
+	})
+	return mux
+}
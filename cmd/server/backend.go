@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"todo-api/internal/config"
+	"todo-api/internal/todo/migration"
+	"todo-api/internal/todo/storagees"
+	"todo-api/internal/todo/storagemem"
+	"todo-api/internal/todo/storagepg"
+	"todo-api/internal/todo/storagesqlite"
+)
+
+// openBackend opens the store of repoType without outbox, replicas or any
+// other extra the server would add, for backups and migrations.
+func openBackend(cfg config.Config, repoType string) (migration.Store, func() error, error) {
+	switch repoType {
+	case "postgres":
+		db, err := sql.Open("pgx", cfg.DSN())
+		if err != nil {
+			return nil, nil, err
+		}
+		return storagepg.New(db), db.Close, nil
+	case "sqlite":
+		if err := os.MkdirAll(filepath.Dir(cfg.SQLitePath), 0o755); err != nil {
+			return nil, nil, err
+		}
+		db, err := storagesqlite.Open(cfg.SQLitePath, cfg.SQLiteBusyTimeout)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := storagesqlite.Migrate(context.Background(), db); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return storagesqlite.New(db), db.Close, nil
+	case "eventsourced":
+		store, err := storagees.Open(cfg.ESDir, cfg.ESSnapshotEvery)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, store.Close, nil
+	case "file":
+		policy, err := storagemem.ParseSyncPolicy(cfg.FileSync)
+		if err != nil {
+			return nil, nil, err
+		}
+		store, err := storagemem.OpenFileStore(cfg.DataDir, storagemem.FileOptions{
+			Sync:         policy,
+			SyncInterval: cfg.FileSyncInterval,
+			CompactEvery: cfg.FileCompactEvery,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, store.Close, nil
+	}
+	return nil, nil, fmt.Errorf("backend %q does not persist todos", repoType)
+}
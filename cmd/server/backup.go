@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"todo-api/internal/backup"
+	"todo-api/internal/config"
+	"todo-api/internal/todo"
+)
+
+const backupUsage = `usage: server backup [flags]
+
+Writes every todo of every tenant of the configured backend into a
+compressed, checksummed archive that any backend can restore.
+The file and eventsourced backends lock their directory, back them up
+while the server is stopped.
+
+flags:
+`
+
+const restoreUsage = `usage: server restore [flags] ARCHIVE
+
+Verifies ARCHIVE and loads it into the configured backend, in a single
+transaction where the backend supports them. The file and eventsourced
+backends lock their directory, restore them while the server is stopped.
+
+flags:
+`
+
+// printProgress reports progress on stderr.
+func printProgress(verb string) func(backup.Progress) {
+	return func(p backup.Progress) {
+		fmt.Fprintf(os.Stderr, "%s %d tenants, %d todos\n", verb, p.Tenants, p.Todos)
+	}
+}
+
+// runBackup implements the "backup" subcommand and returns the exit code.
+func runBackup(cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	out := fs.String("o", "-", "archive to write, - for stdout")
+	repoType := fs.String("repo", cfg.RepoType, "backend to back up")
+	quiet := fs.Bool("q", false, "do not report progress")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), backupUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	store, closeStore, err := openBackend(cfg, *repoType)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer closeStore()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	opts := backup.Options{Source: *repoType}
+	if !*quiet {
+		opts.Progress = printProgress("backed up")
+	}
+	if *out == "-" {
+		if _, err := backup.Write(ctx, os.Stdout, store, opts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	if err := writeArchive(ctx, *out, store, opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// writeArchive writes the archive next to name and renames it into place,
+// so name never holds a partial archive.
+func writeArchive(ctx context.Context, name string, src todo.Exporter, opts backup.Options) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := backup.Write(ctx, f, src, opts); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+var errNotEmpty = errors.New("not empty")
+
+// isEmpty reports whether src holds no todos.
+func isEmpty(ctx context.Context, src todo.Exporter) (bool, error) {
+	err := src.Export(ctx, func(r todo.Record) error {
+		if r.Todo != nil {
+			return errNotEmpty
+		}
+		return nil
+	})
+	if errors.Is(err, errNotEmpty) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// runRestore implements the "restore" subcommand and returns the exit code.
+func runRestore(cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	repoType := fs.String("repo", cfg.RepoType, "backend to restore into")
+	verifyOnly := fs.Bool("verify", false, "only verify the archive")
+	force := fs.Bool("force", false, "restore into a backend that already holds todos, replacing todos with the same id")
+	quiet := fs.Bool("q", false, "do not report progress")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), restoreUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	// verify the whole archive first, a damaged one must not be half restored
+	hdr, p, err := verifyArchive(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "archive ok: version %d, taken %s from %s, %d tenants, %d todos\n",
+		hdr.Version, hdr.CreatedAt.Format("2006-01-02 15:04:05"), hdr.Source, p.Tenants, p.Todos)
+	if *verifyOnly {
+		return 0
+	}
+
+	store, closeStore, err := openBackend(cfg, *repoType)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer closeStore()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if !*force {
+		empty, err := isEmpty(ctx, store)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if !empty {
+			fmt.Fprintf(os.Stderr, "backend %q already holds todos, use -force to restore anyway\n", *repoType)
+			return 1
+		}
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+
+	var progress func(backup.Progress)
+	if !*quiet {
+		progress = printProgress("restored")
+	}
+	restore := func(ctx context.Context) error {
+		// from the start, WithinTx may retry
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		_, _, err := backup.Restore(ctx, f, store, progress)
+		return err
+	}
+	// a restore failing midway must not leave a half restored backend
+	if tx, ok := store.(todo.Transactor); ok {
+		err = tx.WithinTx(ctx, restore)
+	} else {
+		err = restore(ctx)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func verifyArchive(name string) (backup.Header, backup.Progress, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return backup.Header{}, backup.Progress{}, err
+	}
+	defer f.Close()
+	return backup.Verify(f)
+}
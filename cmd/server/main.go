@@ -5,23 +5,33 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"path"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib"
 
 	"todo-api/internal/config"
 	"todo-api/internal/http/middleware"
 	"todo-api/internal/http/router"
+	"todo-api/internal/migrate"
 	"todo-api/internal/todo"
+	"todo-api/internal/todo/cache"
+	"todo-api/internal/todo/migration"
+	"todo-api/internal/todo/outbox"
+	"todo-api/internal/todo/resilience"
+	"todo-api/internal/todo/storagees"
 	"todo-api/internal/todo/storagemem"
 	"todo-api/internal/todo/storagepg"
+	"todo-api/internal/todo/storagesqlite"
+	"todo-api/sql/migrations"
 )
 
 type HealthResponse struct {
@@ -49,7 +59,8 @@ func healthz(w http.ResponseWriter, t *http.Request) {
 }
 
 type ReadyHandler struct {
-	Repo todo.Repository
+	Repo    todo.Repository
+	Breaker *resilience.Breaker // optional
 }
 
 func (h ReadyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -57,60 +68,316 @@ func (h ReadyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	if err := h.Repo.Ping(ctx); err != nil {
+		todo.SetRetryAfter(w.Header(), err)
 		http.Error(w, "storage not ready", http.StatusServiceUnavailable)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	if h.Breaker != nil {
+		fmt.Fprintf(w, `{"status":"ready","circuit":%q}`, h.Breaker.State())
+		return
+	}
 	w.Write([]byte(`{"status":"ready"}`))
 }
 
+type poolStatsHandler struct {
+	pool *pgxpool.Pool
+}
+
+func (h poolStatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(storagepg.Stats(h.pool))
+}
+
+// memOptions enables the in-memory outbox and its relay when configured.
+func memOptions(cfg config.Config) ([]storagemem.Option, *outbox.Relay) {
+	if !cfg.OutboxEnabled {
+		return nil, nil
+	}
+	ob := outbox.NewMemory()
+	relay := outbox.NewRelay(ob, outbox.LogPublisher, cfg.OutboxInterval, 0)
+	return []storagemem.Option{storagemem.WithOutbox(ob)}, relay
+}
+
 func main() {
 	cfg := config.Load()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			os.Exit(runMigrate(cfg, os.Args[2:]))
+		case "backup":
+			os.Exit(runBackup(cfg, os.Args[2:]))
+		case "restore":
+			os.Exit(runRestore(cfg, os.Args[2:]))
+		}
+	}
+
 	var repo todo.Repository
-	if cfg.RepoType == "postgres" {
-		db, err := sql.Open("pgx", cfg.DSN())
+	var relay *outbox.Relay
+	var dbStats http.Handler
+
+	bgCtx, stopBg := context.WithCancel(context.Background())
+	defer stopBg()
+
+	switch cfg.RepoType {
+	case "postgres":
+		poolCfg := storagepg.PoolConfig{
+			MaxConns:               cfg.DBMaxConns,
+			MinConns:               cfg.DBMinConns,
+			MaxConnLifetime:        cfg.DBMaxConnLifetime,
+			MaxConnIdleTime:        cfg.DBMaxConnIdleTime,
+			HealthCheckPeriod:      cfg.DBHealthCheckPeriod,
+			StatementCacheCapacity: cfg.DBStatementCache,
+			ApplicationName:        cfg.DBAppName,
+		}
+		pool, db, err := storagepg.OpenPool(context.Background(), cfg.DSN(), poolCfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer pool.Close()
+		defer db.Close()
+		dbStats = poolStatsHandler{pool}
+		if cfg.MigrateOnStart {
+			m, err := migrate.New(db, migrations.FS)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if _, err := m.Up(context.Background(), false); err != nil {
+				log.Fatal(err)
+			}
+		}
+		var opts []storagepg.Option
+		if cfg.OutboxEnabled {
+			opts = append(opts, storagepg.WithOutbox())
+			relay = outbox.NewRelay(storagepg.NewOutbox(db), outbox.LogPublisher, cfg.OutboxInterval, 0)
+		}
+		var replicas []*sql.DB
+		for _, dsn := range cfg.ReplicaDSNs() {
+			rpool, rdb, err := storagepg.OpenPool(context.Background(), dsn, poolCfg)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer rpool.Close()
+			defer rdb.Close()
+			replicas = append(replicas, rdb)
+		}
+		if len(replicas) > 0 {
+			opts = append(opts, storagepg.WithReplicas(replicas...))
+		}
+		store := storagepg.New(db, opts...)
+		go store.RunReplicaHealthCheck(bgCtx, cfg.DBReplicaCheckInterval)
+		repo = store
+	case "sqlite":
+		if err := os.MkdirAll(filepath.Dir(cfg.SQLitePath), 0o755); err != nil {
+			log.Fatal(err)
+		}
+		db, err := storagesqlite.Open(cfg.SQLitePath, cfg.SQLiteBusyTimeout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		if err := storagesqlite.Migrate(context.Background(), db); err != nil {
+			log.Fatal(err)
+		}
+		var opts []storagesqlite.Option
+		if cfg.OutboxEnabled {
+			opts = append(opts, storagesqlite.WithOutbox())
+			relay = outbox.NewRelay(storagesqlite.NewOutbox(db), outbox.LogPublisher, cfg.OutboxInterval, 0)
+		}
+		repo = storagesqlite.New(db, opts...)
+	case "eventsourced":
+		if cfg.OutboxEnabled {
+			// the event log is not an outbox, nothing would relay its events
+			log.Fatalf("OUTBOX_ENABLED is not supported by the %q backend", cfg.RepoType)
+		}
+		store, err := storagees.Open(cfg.ESDir, cfg.ESSnapshotEvery)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+		repo = store
+	case "file":
+		policy, err := storagemem.ParseSyncPolicy(cfg.FileSync)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var opts []storagemem.Option
+		opts, relay = memOptions(cfg)
+		store, err := storagemem.OpenFileStore(cfg.DataDir, storagemem.FileOptions{
+			Sync:         policy,
+			SyncInterval: cfg.FileSyncInterval,
+			CompactEvery: cfg.FileCompactEvery,
+		}, opts...)
 		if err != nil {
 			log.Fatal(err)
 		}
-		repo = storagepg.New(db)
-	} else {
-		repo = storagemem.NewInMemoryStore()
+		defer store.Close()
+		repo = store
+	default:
+		var opts []storagemem.Option
+		opts, relay = memOptions(cfg)
+		repo = storagemem.NewInMemoryStore(opts...)
 	}
-	handler := todo.NewHandler(repo)
-	readyHandler := ReadyHandler{repo}
 
-	fs := http.FileServer(http.Dir(cfg.StaticDir))
-	http.Handle("/static/", middleware.Logging(http.StripPrefix("/static/", fs)))
+	var migrationCtl *migrationHandler
+	if cfg.MigrateTo != "" {
+		if cfg.OutboxEnabled {
+			// the target has no outbox, domain events would stop after cutover
+			log.Fatal("MIGRATE_TO is not supported with OUTBOX_ENABLED")
+		}
+		old, ok := repo.(migration.Store)
+		if !ok || cfg.MigrateTo == cfg.RepoType {
+			log.Fatalf("cannot migrate from %q to %q", cfg.RepoType, cfg.MigrateTo)
+		}
+		target, closeTarget, err := openBackend(cfg, cfg.MigrateTo)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer closeTarget()
+
+		dual := migration.New(old, target)
+		copier := &migration.Copier{From: old, To: target, Checkpoints: migration.FileCheckpoints(cfg.MigrateCheckpoint)}
+		go func() {
+			if err := copier.Run(bgCtx); err != nil {
+				log.Printf("migration: copy failed: %s", err)
+				return
+			}
+			log.Printf("migration: copy to %s done", cfg.MigrateTo)
+		}()
+		migrationCtl = &migrationHandler{repo: dual, copier: copier}
+		repo = dual
+	}
+
+	if relay != nil {
+		go relay.Run(bgCtx)
+	}
+	var breaker *resilience.Breaker
+	if cfg.ResilienceEnabled {
+		repo = resilience.WithTimeouts(repo, resilience.Timeouts{
+			Read:  cfg.StorageReadTimeout,
+			Write: cfg.StorageWriteTimeout,
+			Ping:  time.Second,
+		})
+		repo = resilience.WithRetry(repo, resilience.RetryOptions{
+			Attempts:  cfg.StorageRetryAttempts,
+			BaseDelay: cfg.StorageRetryBaseDelay,
+			MaxDelay:  cfg.StorageRetryMaxDelay,
+		})
+		breaker = resilience.NewBreaker(repo, resilience.BreakerOptions{
+			FailureThreshold: cfg.BreakerFailureThreshold,
+			OpenTimeout:      cfg.BreakerOpenTimeout,
+		})
+		repo = breaker
+	}
+	var repoCache *cache.Repository
+	if cfg.CacheEnabled {
+		repoCache = cache.New(repo, cache.Options{
+			Size:        cfg.CacheSize,
+			TTL:         cfg.CacheTTL,
+			NegativeTTL: cfg.CacheNegativeTTL,
+		})
+		repo = repoCache
+	}
 
 	mux := &router.Router{}
-	mux.Handle(http.MethodGet, "/ui", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		http.ServeFile(w, r, path.Join(cfg.StaticDir, "form.html"))
-	}))
+	handlerOpts := []todo.HandlerOption{todo.WithURLs(mux)}
+	if cfg.AcceptNumericIDs {
+		handlerOpts = append(handlerOpts, todo.WithNumericIDs())
+	}
+	handler := todo.NewHandler(repo, handlerOpts...)
+	readyHandler := ReadyHandler{Repo: repo, Breaker: breaker}
+
+	mux.Handle(http.MethodGet, "/ui", uiHandler(mux, filepath.Join(cfg.StaticDir, "form.html")))
+	mux.Mount("/static", middleware.Logging(http.FileServer(http.Dir(cfg.StaticDir))))
 
 	mux.Handle(http.MethodGet, "", middleware.Logging(http.HandlerFunc(todo.HelloMessage)))
 	mux.Handle(http.MethodGet, "/healthz", http.HandlerFunc(healthz))
 	mux.Handle(http.MethodGet, "/readyz", readyHandler)
+	adminMux := adminRouter(mux)
+	if dbStats != nil {
+		adminMux.Handle(http.MethodGet, "/stats/db", dbStats)
+	}
+	if repoCache != nil {
+		adminMux.Handle(http.MethodGet, "/stats/cache", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			_ = json.NewEncoder(w).Encode(repoCache.Stats())
+		}))
+	}
+	if migrationCtl != nil {
+		if cfg.AdminAddr == "" {
+			log.Printf("migration: ADMIN_ADDR is empty, the migration endpoints are not served")
+		}
+		// they switch the primary store, so only operators get to them
+		adminMux.Group("/migration", func(m *router.Router) {
+			m.Handle(http.MethodGet, "", http.HandlerFunc(migrationCtl.Status))
+			m.Handle(http.MethodPost, "verify", http.HandlerFunc(migrationCtl.Verify))
+			m.Handle(http.MethodPost, "cutover", http.HandlerFunc(migrationCtl.Cutover))
+			m.Handle(http.MethodPost, "rollback", http.HandlerFunc(migrationCtl.Rollback))
+		})
+	}
+	// trusted resolvers first, the header must not override them
+	var tenants []middleware.TenantResolver
+	if cfg.TenantDomain != "" {
+		tenants = append(tenants, middleware.TenantFromSubdomain(cfg.TenantDomain))
+	}
+	if cfg.TenantHeader != "" {
+		tenants = append(tenants, middleware.TenantFromHeader(cfg.TenantHeader))
+	}
+
 	mux.Group("/api/v1", func(api *router.Router) {
 		api.Use(middleware.Logging)
+		api.Use(middleware.Tenant(cfg.TenantRequired, tenants...))
+		if len(cfg.DBReplicaHosts) > 0 {
+			api.Use(middleware.ReadYourWrites(cfg.ReadYourWritesWindow))
+		}
+		if !cfg.APIV1Deprecated.IsZero() || !cfg.APIV1Sunset.IsZero() {
+			api.Use(middleware.Deprecation(middleware.DeprecationOptions{
+				Since:  cfg.APIV1Deprecated,
+				Sunset: cfg.APIV1Sunset,
+				Link:   cfg.APIV1DeprecationLink,
+			}))
+		}
+		api.Use(middleware.RouteLimits())
 		api.Group("todos", func(todos *router.Router) {
-			todos.Handle(http.MethodPost, "", http.HandlerFunc(handler.Create))
-			todos.Handle(http.MethodGet, ":id", http.HandlerFunc(handler.GetByID))
-			todos.Handle(http.MethodDelete, ":id", http.HandlerFunc(handler.RemoveById))
+			todos.Handle(http.MethodPost, "", http.HandlerFunc(handler.Create), router.Name(todo.RouteTodos),
+				router.BodyLimit(1<<20)) // 1 MB
+			todos.Handle(http.MethodGet, ":id", http.HandlerFunc(handler.GetByID), router.Name(todo.RouteTodo))
+			todos.Handle(http.MethodDelete, ":id", http.HandlerFunc(handler.RemoveById), router.Name(todo.RouteTodo))
 		})
 	})
+	if err := mux.Err(); err != nil {
+		log.Fatal(err)
+	}
 
 	addr := cfg.Port
 	if !strings.HasPrefix(addr, ":") {
 		addr = ":" + addr
 	}
 
+	var root http.Handler = mux
+	if len(cfg.CORSAllowedOrigins) > 0 {
+		corsOpts := middleware.CORSOptions{
+			AllowedOrigins:   cfg.CORSAllowedOrigins,
+			AllowedHeaders:   cfg.CORSAllowedHeaders,
+			ExposedHeaders:   cfg.CORSExposedHeaders,
+			AllowCredentials: cfg.CORSAllowCredentials,
+			MaxAge:           cfg.CORSMaxAge,
+		}
+		if err := corsOpts.Validate(); err != nil {
+			log.Fatal(err)
+		}
+		// in front of the router, which answers the preflight requests
+		root = middleware.CORS(corsOpts)(mux)
+	}
+
 	log.Println("Starting on port", addr)
 	srv := &http.Server{
 		Addr:              addr,
-		Handler:           mux,
+		Handler:           root,
 		ReadHeaderTimeout: 5 * time.Second,
 		ReadTimeout:       10 * time.Second,
 		WriteTimeout:      15 * time.Second,
@@ -123,6 +390,17 @@ func main() {
 	}()
 	log.Println("Server started on ", addr)
 
+	var admin *http.Server
+	if cfg.AdminAddr != "" {
+		admin = adminServer(cfg.AdminAddr, adminMux)
+		go func() {
+			if err := admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("admin: %s", err)
+			}
+		}()
+		log.Println("Admin listening on", cfg.AdminAddr)
+	}
+
 	// What is going on?
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
@@ -132,9 +410,13 @@ func main() {
 	defer cancel()
 
 	log.Println("Shutting down gracefully...")
+	stopBg()
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Println("Forced shutdown: ", err)
 	}
+	if admin != nil {
+		_ = admin.Shutdown(ctx)
+	}
 	log.Println("Server stopped")
 	signal.Stop(stop)
 }
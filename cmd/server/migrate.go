@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+
+	"todo-api/internal/config"
+	"todo-api/internal/migrate"
+	"todo-api/sql/migrations"
+)
+
+const migrateUsage = `usage: server migrate [flags] up|down|status
+
+flags:
+`
+
+// runMigrate implements the "migrate" subcommand and returns the exit code.
+func runMigrate(cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only print what would be done")
+	steps := fs.Int("steps", 1, "number of migrations to revert with down")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), migrateUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	db, err := sql.Open("pgx", cfg.DSN())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer db.Close()
+
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx := context.Background()
+	prefix := ""
+	if *dryRun {
+		prefix = "(dry run) "
+	}
+
+	switch fs.Arg(0) {
+	case "up":
+		done, err := m.Up(ctx, *dryRun)
+		for _, mg := range done {
+			fmt.Printf("%sup   %03d_%s\n", prefix, mg.Version, mg.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "down":
+		done, err := m.Down(ctx, *steps, *dryRun)
+		for _, mg := range done {
+			fmt.Printf("%sdown %03d_%s\n", prefix, mg.Version, mg.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "status":
+		st, err := m.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, s := range st {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%-8s %03d_%s\n", state, s.Version, s.Name)
+		}
+	default:
+		fs.Usage()
+		return 2
+	}
+	return 0
+}
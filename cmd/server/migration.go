@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"todo-api/internal/todo/migration"
+)
+
+// migrationHandler controls an online migration between backends.
+type migrationHandler struct {
+	repo   *migration.Repository
+	copier *migration.Copier
+}
+
+type migrationStatus struct {
+	migration.Stats
+	Copy migration.CopyProgress `json:"copy"`
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h migrationHandler) Status(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, migrationStatus{Stats: h.repo.Stats(), Copy: h.copier.Progress()})
+}
+
+// Verify compares both backends, with ?repair=true it also fixes the
+// secondary.
+func (h migrationHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	repair, _ := strconv.ParseBool(r.URL.Query().Get("repair"))
+	rep, err := h.repo.Verify(r.Context(), repair)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, rep)
+}
+
+// Cutover switches reads to the new backend. It is refused while the copy is
+// running unless ?force=true is given.
+func (h migrationHandler) Cutover(w http.ResponseWriter, r *http.Request) {
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	if !h.copier.Progress().Done && !force {
+		http.Error(w, "copy still running", http.StatusConflict)
+		return
+	}
+	h.repo.Cutover()
+	h.Status(w, r)
+}
+
+func (h migrationHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	h.repo.Rollback()
+	h.Status(w, r)
+}
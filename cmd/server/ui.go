@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+
+	"todo-api/internal/http/router"
+	"todo-api/internal/todo"
+)
+
+// uiHandler serves the form page at file, a template that gets the paths of
+// the API from the router so they can't drift from the routes.
+func uiHandler(mux *router.Router, file string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tmpl, err := template.ParseFiles(file)
+		if err != nil {
+			log.Printf("ui: %s", err)
+			http.Error(w, "page not available", http.StatusInternalServerError)
+			return
+		}
+		todosURL, err := mux.URL(todo.RouteTodos)
+		if err != nil {
+			log.Printf("ui: %s", err)
+			http.Error(w, "page not available", http.StatusInternalServerError)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, struct{ TodosURL string }{todosURL}); err != nil {
+			log.Printf("ui: %s", err)
+			http.Error(w, "page not available", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(buf.Bytes())
+	})
+}
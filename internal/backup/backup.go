@@ -0,0 +1,280 @@
+// Package backup writes and reads archives of a todo store in a format that
+// does not depend on the backend, so a backup of one backend can be restored
+// into any other.
+//
+// An archive is a gzip compressed stream of JSON lines: a header, one line
+// per tenant counter or todo and a trailer holding the record counts and the
+// SHA-256 of every line before it.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+	"todo-api/internal/todo"
+)
+
+const (
+	Format  = "todo-api-backup"
+	Version = 1
+)
+
+// progressEvery is the number of records between two progress reports.
+const progressEvery = 1000
+
+var (
+	ErrFormat    = errors.New("backup: not a backup archive")
+	ErrTruncated = errors.New("backup: archive is truncated")
+	ErrChecksum  = errors.New("backup: checksum mismatch")
+)
+
+type Header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	// Source is the backend the archive was taken from, informational only.
+	Source string `json:"source,omitempty"`
+}
+
+type Trailer struct {
+	Tenants int64  `json:"tenants"`
+	Todos   int64  `json:"todos"`
+	SHA256  string `json:"sha256"`
+}
+
+// Progress counts the records written or read so far.
+type Progress struct {
+	Tenants int64
+	Todos   int64
+}
+
+func (p Progress) records() int64 {
+	return p.Tenants + p.Todos
+}
+
+// line is one line of an archive, exactly one field is set.
+type line struct {
+	Header  *Header     `json:"header,omitempty"`
+	Tenant  *tenantLine `json:"tenant,omitempty"`
+	Todo    *todoLine   `json:"todo,omitempty"`
+	Trailer *Trailer    `json:"trailer,omitempty"`
+}
+
+type tenantLine struct {
+	Name   string `json:"name"`
+	LastID int64  `json:"last_id"`
+}
+
+// todoLine spells out the fields of a todo, so the archive does not change
+// with the JSON encoding of todo.Todo.
+type todoLine struct {
+	Tenant      string    `json:"tenant"`
+	ID          int64     `json:"id"`
+	PublicID    string    `json:"public_id"`
+	Title       string    `json:"title"`
+	Description *string   `json:"description,omitempty"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Options struct {
+	Source string
+	// Progress, when set, is called every few records and once at the end.
+	Progress func(Progress)
+}
+
+// Write streams every record of src into w as an archive.
+// w is not closed.
+func Write(ctx context.Context, w io.Writer, src todo.Exporter, opts Options) (Progress, error) {
+	gz := gzip.NewWriter(w)
+	sum := sha256.New()
+	enc := encoder{w: io.MultiWriter(gz, sum)}
+
+	enc.write(line{Header: &Header{
+		Format:    Format,
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+		Source:    opts.Source,
+	}})
+
+	var p Progress
+	err := src.Export(ctx, func(r todo.Record) error {
+		if r.Todo == nil {
+			enc.write(line{Tenant: &tenantLine{Name: r.Tenant, LastID: r.LastID}})
+			p.Tenants++
+		} else {
+			t := r.Todo
+			enc.write(line{Todo: &todoLine{
+				Tenant:      r.Tenant,
+				ID:          t.ID,
+				PublicID:    t.PublicID,
+				Title:       t.Title,
+				Description: t.Description,
+				Status:      t.Status,
+				CreatedAt:   t.CreatedAt.UTC(),
+				UpdatedAt:   t.UpdatedAt.UTC(),
+			}})
+			p.Todos++
+		}
+		if opts.Progress != nil && p.records()%progressEvery == 0 {
+			opts.Progress(p)
+		}
+		return enc.err
+	})
+	if err != nil {
+		return p, err
+	}
+
+	// the trailer is not part of its own checksum
+	enc.w = gz
+	enc.write(line{Trailer: &Trailer{
+		Tenants: p.Tenants,
+		Todos:   p.Todos,
+		SHA256:  hex.EncodeToString(sum.Sum(nil)),
+	}})
+	if enc.err != nil {
+		return p, enc.err
+	}
+	if err := gz.Close(); err != nil {
+		return p, err
+	}
+	if opts.Progress != nil {
+		opts.Progress(p)
+	}
+	return p, nil
+}
+
+// encoder writes lines and keeps the first error.
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+func (e *encoder) write(l line) {
+	if e.err != nil {
+		return
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		e.err = err
+		return
+	}
+	_, e.err = e.w.Write(append(b, '\n'))
+}
+
+// Read streams the records of the archive in r to fn and verifies the
+// archive when it reaches the trailer. fn has seen every record by the time a
+// damaged archive is detected, use Verify first when that matters.
+func Read(ctx context.Context, r io.Reader, fn func(todo.Record) error, progress func(Progress)) (Header, Progress, error) {
+	var p Progress
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Header{}, p, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	defer gz.Close()
+
+	dec := decoder{r: bufio.NewReader(gz), sum: sha256.New()}
+	first, err := dec.next()
+	if err != nil || first.Header == nil || first.Header.Format != Format {
+		return Header{}, p, ErrFormat
+	}
+	hdr := *first.Header
+	if hdr.Version > Version {
+		return hdr, p, fmt.Errorf("backup: unsupported archive version %d", hdr.Version)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return hdr, p, err
+		}
+		want := hex.EncodeToString(dec.sum.Sum(nil))
+		l, err := dec.next()
+		if err != nil {
+			return hdr, p, err
+		}
+
+		var rec todo.Record
+		switch {
+		case l.Trailer != nil:
+			if l.Trailer.SHA256 != want || l.Trailer.Tenants != p.Tenants || l.Trailer.Todos != p.Todos {
+				return hdr, p, ErrChecksum
+			}
+			if _, err := dec.r.Peek(1); !errors.Is(err, io.EOF) {
+				return hdr, p, fmt.Errorf("%w: data after trailer", ErrFormat)
+			}
+			if progress != nil {
+				progress(p)
+			}
+			return hdr, p, nil
+		case l.Tenant != nil:
+			rec = todo.Record{Tenant: l.Tenant.Name, LastID: l.Tenant.LastID}
+			p.Tenants++
+		case l.Todo != nil:
+			t := l.Todo
+			rec = todo.Record{Tenant: t.Tenant, Todo: &todo.Todo{
+				ID:          t.ID,
+				PublicID:    t.PublicID,
+				Title:       t.Title,
+				Description: t.Description,
+				Status:      t.Status,
+				CreatedAt:   t.CreatedAt,
+				UpdatedAt:   t.UpdatedAt,
+			}}
+			p.Todos++
+		default:
+			return hdr, p, fmt.Errorf("%w: unknown line", ErrFormat)
+		}
+
+		if err := fn(rec); err != nil {
+			return hdr, p, err
+		}
+		if progress != nil && p.records()%progressEvery == 0 {
+			progress(p)
+		}
+	}
+}
+
+// Verify reads the whole archive in r and checks its integrity.
+func Verify(r io.Reader) (Header, Progress, error) {
+	return Read(context.Background(), r, func(todo.Record) error { return nil }, nil)
+}
+
+// Restore imports every record of the archive in r into dst.
+func Restore(ctx context.Context, r io.Reader, dst todo.Importer, progress func(Progress)) (Header, Progress, error) {
+	return Read(ctx, r, func(rec todo.Record) error {
+		return dst.Import(ctx, rec)
+	}, progress)
+}
+
+// decoder reads lines and hashes them as they are read.
+type decoder struct {
+	r   *bufio.Reader
+	sum hash.Hash
+}
+
+func (d *decoder) next() (line, error) {
+	b, err := d.r.ReadBytes('\n')
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return line{}, ErrTruncated
+	}
+	if err != nil {
+		return line{}, err
+	}
+	d.sum.Write(b)
+
+	l := line{}
+	if err := json.Unmarshal(b, &l); err != nil {
+		return line{}, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	return l, nil
+}
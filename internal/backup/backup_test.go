@@ -0,0 +1,188 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+	"todo-api/internal/todo/storagemem"
+	"todo-api/internal/todo/storagesqlite"
+)
+
+func seed(t *testing.T, repo todo.Repository) {
+	t.Helper()
+
+	desc := "with description"
+	for _, tenant := range []string{pkg.DefaultTenant, "acme"} {
+		ctx := pkg.WithTenant(context.Background(), tenant)
+		for _, in := range []todo.Todo{{Title: "a"}, {Title: "b", Description: &desc, Status: "done"}, {Title: "c"}} {
+			if _, err := repo.Create(ctx, in); err != nil {
+				t.Fatalf("Create() err = %v", err)
+			}
+		}
+		// the counter must survive the removal of the newest todo
+		if err := repo.Remove(ctx, 3); err != nil {
+			t.Fatalf("Remove() err = %v", err)
+		}
+	}
+}
+
+// export returns the records of src ordered by tenant.
+func export(t *testing.T, src todo.Exporter) []todo.Record {
+	t.Helper()
+
+	var out []todo.Record
+	err := src.Export(context.Background(), func(r todo.Record) error {
+		if r.Todo != nil {
+			cp := *r.Todo
+			cp.CreatedAt = cp.CreatedAt.Truncate(time.Microsecond)
+			cp.UpdatedAt = cp.UpdatedAt.Truncate(time.Microsecond)
+			r.Todo = &cp
+		}
+		out = append(out, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Export() err = %v", err)
+	}
+	// backends only agree on the order within a tenant
+	slices.SortStableFunc(out, func(a, b todo.Record) int {
+		return strings.Compare(a.Tenant, b.Tenant)
+	})
+	return out
+}
+
+func openSQLite(t *testing.T) *storagesqlite.SQLiteStore {
+	t.Helper()
+
+	db, err := storagesqlite.Open(filepath.Join(t.TempDir(), "todos.db"), time.Second)
+	if err != nil {
+		t.Fatalf("Open() err = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := storagesqlite.Migrate(context.Background(), db); err != nil {
+		t.Fatalf("Migrate() err = %v", err)
+	}
+	return storagesqlite.New(db)
+}
+
+func TestRoundTrip(t *testing.T) {
+	src := storagemem.NewInMemoryStore()
+	seed(t, src)
+
+	var buf bytes.Buffer
+	var reports int
+	p, err := Write(context.Background(), &buf, src, Options{Source: "memory", Progress: func(Progress) { reports++ }})
+	if err != nil {
+		t.Fatalf("Write() err = %v", err)
+	}
+	if want := (Progress{Tenants: 2, Todos: 4}); p != want {
+		t.Fatalf("Write() = %+v, want %+v", p, want)
+	}
+	if reports == 0 {
+		t.Fatalf("progress not reported")
+	}
+
+	hdr, _, err := Verify(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Verify() err = %v", err)
+	}
+	if hdr.Version != Version || hdr.Source != "memory" {
+		t.Fatalf("Verify() header = %+v", hdr)
+	}
+
+	dst := openSQLite(t)
+	if _, _, err := Restore(context.Background(), bytes.NewReader(buf.Bytes()), dst, nil); err != nil {
+		t.Fatalf("Restore() err = %v", err)
+	}
+	if got, want := export(t, dst), export(t, src); !reflect.DeepEqual(got, want) {
+		t.Fatalf("restored = %+v, want %+v", got, want)
+	}
+
+	// and back into a persisted in-memory store
+	buf.Reset()
+	if _, err := Write(context.Background(), &buf, dst, Options{}); err != nil {
+		t.Fatalf("Write() err = %v", err)
+	}
+	dir := t.TempDir()
+	file, err := storagemem.OpenFileStore(dir, storagemem.FileOptions{})
+	if err != nil {
+		t.Fatalf("OpenFileStore() err = %v", err)
+	}
+	if _, _, err := Restore(context.Background(), &buf, file, nil); err != nil {
+		t.Fatalf("Restore() err = %v", err)
+	}
+	file.Close()
+	file, err = storagemem.OpenFileStore(dir, storagemem.FileOptions{})
+	if err != nil {
+		t.Fatalf("OpenFileStore() err = %v", err)
+	}
+	defer file.Close()
+	if got, want := export(t, file), export(t, src); !reflect.DeepEqual(got, want) {
+		t.Fatalf("restored = %+v, want %+v", got, want)
+	}
+
+	created, err := file.Create(pkg.WithTenant(context.Background(), "acme"), todo.Todo{Title: "d"})
+	if err != nil {
+		t.Fatalf("Create() err = %v", err)
+	}
+	if created.ID != 4 {
+		t.Fatalf("Create() id = %d, want 4", created.ID)
+	}
+}
+
+// rewrite decompresses an archive, applies fn and compresses it again.
+func rewrite(t *testing.T, archive []byte, fn func([]byte) []byte) []byte {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() err = %v", err)
+	}
+	plain, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("ReadAll() err = %v", err)
+	}
+
+	var out bytes.Buffer
+	w := gzip.NewWriter(&out)
+	w.Write(fn(plain))
+	w.Close()
+	return out.Bytes()
+}
+
+func TestVerify_Damaged(t *testing.T) {
+	src := storagemem.NewInMemoryStore()
+	seed(t, src)
+	var buf bytes.Buffer
+	if _, err := Write(context.Background(), &buf, src, Options{}); err != nil {
+		t.Fatalf("Write() err = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		fn   func([]byte) []byte
+		want error
+	}{
+		{"altered", func(b []byte) []byte { return bytes.Replace(b, []byte(`"title":"b"`), []byte(`"title":"x"`), 1) }, ErrChecksum},
+		{"truncated", func(b []byte) []byte { return b[:bytes.LastIndex(b, []byte(`{"trailer"`))] }, ErrTruncated},
+		{"not an archive", func(b []byte) []byte { return []byte("{}\n") }, ErrFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := rewrite(t, buf.Bytes(), tt.fn)
+			if _, _, err := Verify(bytes.NewReader(archive)); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -19,11 +21,98 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	DBSSLMode  string
+
+	// DBReplicaHosts are host[:port] of read replicas, sharing user, password
+	// and database name with the primary.
+	DBReplicaHosts         []string
+	DBReplicaCheckInterval time.Duration
+	ReadYourWritesWindow   time.Duration
+
+	DBMaxConns          int32
+	DBMinConns          int32
+	DBMaxConnLifetime   time.Duration
+	DBMaxConnIdleTime   time.Duration
+	DBHealthCheckPeriod time.Duration
+	DBStatementCache    int
+	DBAppName           string
+
+	MigrateOnStart bool
+
+	OutboxEnabled  bool
+	OutboxInterval time.Duration
+
+	ESDir           string
+	ESSnapshotEvery int
+
+	DataDir          string
+	FileSync         string
+	FileSyncInterval time.Duration
+	FileCompactEvery int
+
+	SQLitePath        string
+	SQLiteBusyTimeout time.Duration
+
+	CacheEnabled     bool
+	CacheSize        int
+	CacheTTL         time.Duration
+	CacheNegativeTTL time.Duration
+
+	ResilienceEnabled       bool
+	StorageReadTimeout      time.Duration
+	StorageWriteTimeout     time.Duration
+	StorageRetryAttempts    int
+	StorageRetryBaseDelay   time.Duration
+	StorageRetryMaxDelay    time.Duration
+	BreakerFailureThreshold int
+	BreakerOpenTimeout      time.Duration
+
+	// TenantHeader names a header clients may pick their tenant with, ""
+	// disables it. Only for deployments behind a proxy that sets it.
+	TenantHeader   string
+	TenantDomain   string // tenants are subdomains of it, "" disables
+	TenantRequired bool
+
+	// AcceptNumericIDs keeps accepting internal ids in URLs next to public ids.
+	AcceptNumericIDs bool
+
+	// MigrateTo is the backend an online migration moves to, "" for none.
+	MigrateTo         string
+	MigrateCheckpoint string
+
+	// CORSAllowedOrigins enables CORS for these origins, "*" matches any text.
+	CORSAllowedOrigins   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+
+	// AdminAddr is where the debug endpoints listen, "" disables them.
+	AdminAddr string
+
+	// APIV1Deprecated marks /api/v1 as deprecated since then, zero for not.
+	APIV1Deprecated      time.Time
+	APIV1Sunset          time.Time
+	APIV1DeprecationLink string
 }
 
 func (c Config) DSN() string {
-	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
-		c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName, c.DBSSLMode)
+	return c.dsn(fmt.Sprintf("%s:%d", c.DBHost, c.DBPort))
+}
+
+func (c Config) ReplicaDSNs() []string {
+	dsns := make([]string, 0, len(c.DBReplicaHosts))
+	for _, h := range c.DBReplicaHosts {
+		if !strings.Contains(h, ":") {
+			h = fmt.Sprintf("%s:%d", h, c.DBPort)
+		}
+		dsns = append(dsns, c.dsn(h))
+	}
+	return dsns
+}
+
+func (c Config) dsn(hostPort string) string {
+	return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
+		c.DBUser, c.DBPassword, hostPort, c.DBName, c.DBSSLMode)
 }
 
 func Load() Config {
@@ -51,6 +140,68 @@ func Load() Config {
 		cfg.DBPort = 5432
 	}
 
+	cfg.DBReplicaHosts = getList("DB_REPLICA_HOSTS", "")
+	cfg.DBReplicaCheckInterval = getDuration("DB_REPLICA_CHECK_INTERVAL", 5*time.Second)
+	cfg.ReadYourWritesWindow = getDuration("READ_YOUR_WRITES_WINDOW", 5*time.Second)
+
+	cfg.DBMaxConns = int32(getInt("DB_MAX_CONNS", 10))
+	cfg.DBMinConns = int32(getInt("DB_MIN_CONNS", 0))
+	cfg.DBMaxConnLifetime = getDuration("DB_MAX_CONN_LIFETIME", time.Hour)
+	cfg.DBMaxConnIdleTime = getDuration("DB_MAX_CONN_IDLE_TIME", 30*time.Minute)
+	cfg.DBHealthCheckPeriod = getDuration("DB_HEALTH_CHECK_PERIOD", time.Minute)
+	cfg.DBStatementCache = getInt("DB_STATEMENT_CACHE", 512)
+	cfg.DBAppName = getEnv("DB_APP_NAME", "todo-api")
+
+	cfg.MigrateOnStart, _ = strconv.ParseBool(getEnv("MIGRATE_ON_START", "false"))
+	cfg.OutboxEnabled, _ = strconv.ParseBool(getEnv("OUTBOX_ENABLED", "false"))
+	cfg.OutboxInterval = getDuration("OUTBOX_INTERVAL", time.Second)
+
+	cfg.ESDir = getEnv("ES_DIR", "data/events")
+	cfg.ESSnapshotEvery = getInt("ES_SNAPSHOT_EVERY", 1000)
+
+	cfg.DataDir = getEnv("DATA_DIR", "data/todos")
+	cfg.FileSync = getEnv("FILE_SYNC", "always")
+	cfg.FileSyncInterval = getDuration("FILE_SYNC_INTERVAL", time.Second)
+	cfg.FileCompactEvery = getInt("FILE_COMPACT_EVERY", 10000)
+
+	cfg.SQLitePath = getEnv("SQLITE_PATH", "data/todos.db")
+	cfg.SQLiteBusyTimeout = getDuration("SQLITE_BUSY_TIMEOUT", 5*time.Second)
+
+	cfg.CacheEnabled, _ = strconv.ParseBool(getEnv("CACHE_ENABLED", "false"))
+	cfg.CacheSize = getInt("CACHE_SIZE", 1000)
+	cfg.CacheTTL = getDuration("CACHE_TTL", 30*time.Second)
+	cfg.CacheNegativeTTL = getDuration("CACHE_NEGATIVE_TTL", 5*time.Second)
+
+	cfg.ResilienceEnabled, _ = strconv.ParseBool(getEnv("RESILIENCE_ENABLED", "false"))
+	cfg.StorageReadTimeout = getDuration("STORAGE_READ_TIMEOUT", 2*time.Second)
+	cfg.StorageWriteTimeout = getDuration("STORAGE_WRITE_TIMEOUT", 5*time.Second)
+	cfg.StorageRetryAttempts = getInt("STORAGE_RETRY_ATTEMPTS", 3)
+	cfg.StorageRetryBaseDelay = getDuration("STORAGE_RETRY_BASE_DELAY", 50*time.Millisecond)
+	cfg.StorageRetryMaxDelay = getDuration("STORAGE_RETRY_MAX_DELAY", time.Second)
+	cfg.BreakerFailureThreshold = getInt("BREAKER_FAILURE_THRESHOLD", 5)
+	cfg.BreakerOpenTimeout = getDuration("BREAKER_OPEN_TIMEOUT", 10*time.Second)
+
+	cfg.TenantHeader = getEnv("TENANT_HEADER", "")
+	cfg.TenantDomain = getEnv("TENANT_DOMAIN", "")
+	cfg.TenantRequired, _ = strconv.ParseBool(getEnv("TENANT_REQUIRED", "false"))
+
+	cfg.AcceptNumericIDs, _ = strconv.ParseBool(getEnv("ACCEPT_NUMERIC_IDS", "false"))
+
+	cfg.MigrateTo = getEnv("MIGRATE_TO", "")
+	cfg.MigrateCheckpoint = getEnv("MIGRATE_CHECKPOINT", "data/migration.json")
+
+	cfg.CORSAllowedOrigins = getList("CORS_ALLOWED_ORIGINS", "")
+	cfg.CORSAllowedHeaders = getList("CORS_ALLOWED_HEADERS", "Content-Type,X-Consistency-Token")
+	cfg.CORSExposedHeaders = getList("CORS_EXPOSED_HEADERS", "X-Consistency-Token,Retry-After,Location")
+	cfg.CORSAllowCredentials, _ = strconv.ParseBool(getEnv("CORS_ALLOW_CREDENTIALS", "false"))
+	cfg.CORSMaxAge = getDuration("CORS_MAX_AGE", 10*time.Minute)
+
+	cfg.AdminAddr = getEnv("ADMIN_ADDR", "127.0.0.1:9090")
+
+	cfg.APIV1Deprecated = getDate("API_V1_DEPRECATED")
+	cfg.APIV1Sunset = getDate("API_V1_SUNSET")
+	cfg.APIV1DeprecationLink = getEnv("API_V1_DEPRECATION_LINK", "")
+
 	return cfg
 }
 
@@ -60,3 +211,40 @@ func getEnv(key, def string) string {
 	}
 	return def
 }
+
+func getDuration(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(getEnv(key, "")); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// getInt returns a non-negative integer from the environment or def.
+func getInt(key string, def int) int {
+	if n, err := strconv.Atoi(getEnv(key, "")); err == nil && n >= 0 {
+		return n
+	}
+	return def
+}
+
+// getDate returns a date or RFC 3339 time from the environment, zero if it
+// is unset or invalid.
+func getDate(key string) time.Time {
+	v := getEnv(key, "")
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t
+	}
+	t, _ := time.Parse(time.DateOnly, v)
+	return t
+}
+
+// getList returns the non-empty items of a comma separated variable.
+func getList(key, def string) []string {
+	var list []string
+	for _, s := range strings.Split(getEnv(key, def), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
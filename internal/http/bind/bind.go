@@ -0,0 +1,363 @@
+// Package bind fills request structs from the route parameters, query
+// string, headers and JSON body of a request.
+package bind
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	httpx "todo-api/internal/http"
+	"todo-api/internal/pkg"
+)
+
+// Sources of a field, as reported in httpx.FieldError.
+const (
+	Path   = "path"
+	Query  = "query"
+	Header = "header"
+	Body   = "body"
+)
+
+// Error is a request that could not be bound. Status is 415 or 413 for a
+// body of the wrong type or size, 400 for input that can't be decoded and
+// 422 for input breaking a validation rule.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Fields  []httpx.FieldError
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Source + " " + f.Field + " " + f.Message
+	}
+	return e.Message + ": " + strings.Join(parts, "; ")
+}
+
+// WriteError writes err as returned by Bind.
+func WriteError(w http.ResponseWriter, err error) {
+	var be *Error
+	if !errors.As(err, &be) {
+		httpx.WriteError(w, http.StatusInternalServerError, "internal_error", "internal server error")
+		return
+	}
+	httpx.WriteFieldErrors(w, be.Status, be.Code, be.Message, be.Fields)
+}
+
+// Bind fills the struct dst points to from r. Fields are read from
+//
+//	path:"id"          a parameter of the matched route
+//	query:"status"     the query string, slices take repeated values
+//	header:"If-Match"  a request header, slices take repeated values
+//	json:"title"       the JSON body, as is every other exported field
+//
+// An input that is missing or empty takes the value of the default tag, or
+// the zero value. The validate tag lists rules checked after decoding:
+//
+//	required   the value is not the zero value
+//	trim       strip surrounding white space from a string first
+//	min=n      at least n characters, elements or, for numbers, n
+//	max=n      at most n
+//	oneof=a b  one of the values separated by spaces
+//
+// Rules other than required skip nil pointers. The body is only read when
+// dst has body fields; it must be a single JSON value without unknown
+// fields, and application/json if it has a Content-Type. Bind returns an
+// *Error for bad input and panics on malformed tags.
+func Bind(r *http.Request, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("bind: %T is not a pointer to a struct", dst))
+	}
+	v = v.Elem()
+	s := structOf(v.Type())
+
+	if s.body != nil {
+		if err := decodeBody(r, v, s); err != nil {
+			return err
+		}
+	}
+
+	var bad []httpx.FieldError
+	var params map[string]string
+	if scope := pkg.ScopeFrom(r); scope != nil {
+		params = scope.Params
+	}
+	query := r.URL.Query()
+	for _, f := range s.fields {
+		fv := v.Field(f.index)
+		var vals []string
+		switch f.source {
+		case Path:
+			if p, ok := params[f.name]; ok {
+				vals = []string{p}
+			}
+		case Query:
+			vals = query[f.name]
+		case Header:
+			vals = r.Header.Values(f.name)
+		case Body:
+			// already decoded, only the default is left to apply
+			if !fv.IsZero() || f.def == nil {
+				continue
+			}
+		}
+		if absent(vals) {
+			vals = f.def
+		}
+
+		fv.SetZero()
+		if vals == nil {
+			continue
+		}
+		if err := set(fv, vals); err != nil {
+			bad = append(bad, httpx.FieldError{Field: f.name, Source: f.source, Message: err.Error()})
+		}
+	}
+	if bad != nil {
+		return &Error{Status: http.StatusBadRequest, Code: "invalid_parameters", Message: "invalid request parameters", Fields: bad}
+	}
+
+	for _, f := range s.fields {
+		if msg := check(v.Field(f.index), f); msg != "" {
+			bad = append(bad, httpx.FieldError{Field: f.name, Source: f.source, Message: msg})
+		}
+	}
+	if bad != nil {
+		return &Error{Status: http.StatusUnprocessableEntity, Code: "invalid_content", Message: "request failed validation", Fields: bad}
+	}
+	return nil
+}
+
+func absent(vals []string) bool {
+	return len(vals) == 0 || len(vals) == 1 && vals[0] == ""
+}
+
+func decodeBody(r *http.Request, v reflect.Value, s *structInfo) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil || mt != "application/json" && !strings.HasSuffix(mt, "+json") {
+			return &Error{Status: http.StatusUnsupportedMediaType, Code: "unsupported_media_type", Message: "expect application/json"}
+		}
+	}
+	if r.Body == nil {
+		return &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: "request body required"}
+	}
+
+	// decode into a struct of the body fields only, so that JSON can't set
+	// path, query or header fields
+	body := reflect.New(s.body)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(body.Interface()); err != nil {
+		return bodyError(err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return bodyError(err)
+		}
+		return &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: "multiple json values"}
+	}
+
+	for i, f := range s.bodyFields {
+		v.Field(f.index).Set(body.Elem().Field(i))
+	}
+	return nil
+}
+
+func bodyError(err error) error {
+	var (
+		tooLarge *http.MaxBytesError
+		typeErr  *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &tooLarge):
+		return &Error{Status: http.StatusRequestEntityTooLarge, Code: "body_too_large", Message: fmt.Sprintf("body exceeds %d bytes", tooLarge.Limit)}
+	case errors.Is(err, io.EOF):
+		return &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: "request body required"}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: "unable to process json", Fields: []httpx.FieldError{{
+			Field: typeErr.Field, Source: Body, Message: "must be " + typeName(typeErr.Type),
+		}}}
+	}
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: "unable to process json", Fields: []httpx.FieldError{{
+			Field: strings.Trim(name, `"`), Source: Body, Message: "is unknown",
+		}}}
+	}
+	return &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: "unable to process json"}
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// set stores vals in v, converting them to its type.
+func set(v reflect.Value, vals []string) error {
+	if v.Kind() == reflect.Slice && !v.Type().Implements(textUnmarshalerType) &&
+		!reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setOne(s.Index(i), val); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	if len(vals) > 1 {
+		return errors.New("must be given once")
+	}
+	return setOne(v, vals[0])
+}
+
+func setOne(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setOne(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return errors.New("must be " + typeName(v.Type()))
+		}
+		return nil
+	}
+
+	var err error
+	switch {
+	case v.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(s)
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		v.SetBool(b)
+	case v.CanInt():
+		var n int64
+		n, err = strconv.ParseInt(s, 10, v.Type().Bits())
+		v.SetInt(n)
+	case v.CanUint():
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, v.Type().Bits())
+		v.SetUint(n)
+	case v.CanFloat():
+		var f float64
+		f, err = strconv.ParseFloat(s, v.Type().Bits())
+		v.SetFloat(f)
+	default:
+		panic("bind: unsupported field type " + v.Type().String())
+	}
+	if err != nil {
+		return errors.New("must be " + typeName(v.Type()))
+	}
+	return nil
+}
+
+// typeName describes t for an error message.
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		return "a duration"
+	case t == reflect.TypeFor[time.Time]():
+		return "an RFC 3339 time"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a non-negative integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return "a valid " + t.String()
+}
+
+// check applies the rules of f to v and returns why it fails, if it does.
+func check(v reflect.Value, f *fieldInfo) string {
+	for _, r := range f.rules {
+		if r.name == "required" {
+			if v.IsZero() {
+				return "is required"
+			}
+			continue
+		}
+
+		e := v
+		for e.Kind() == reflect.Pointer {
+			if e.IsNil() {
+				return ""
+			}
+			e = e.Elem()
+		}
+		switch r.name {
+		case "trim":
+			e.SetString(strings.TrimSpace(e.String()))
+		case "min", "max":
+			n, unit := measure(e)
+			if r.name == "min" && n < r.num {
+				return fmt.Sprintf("must be at least %s%s", r.arg, unit)
+			}
+			if r.name == "max" && n > r.num {
+				return fmt.Sprintf("must be at most %s%s", r.arg, unit)
+			}
+		case "oneof":
+			s := fmt.Sprint(e.Interface())
+			if !strings.Contains(" "+r.arg+" ", " "+s+" ") {
+				return "must be one of " + strings.Join(strings.Fields(r.arg), ", ")
+			}
+		}
+	}
+	return ""
+}
+
+// measure returns what min and max compare for v, and its unit.
+func measure(v reflect.Value) (float64, string) {
+	switch {
+	case v.Kind() == reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters long"
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Map:
+		return float64(v.Len()), " elements"
+	case v.Type() == durationType:
+		return float64(v.Int()), "ns"
+	case v.CanInt():
+		return float64(v.Int()), ""
+	case v.CanUint():
+		return float64(v.Uint()), ""
+	}
+	return v.Float(), ""
+}
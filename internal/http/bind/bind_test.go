@@ -0,0 +1,200 @@
+package bind
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	httpx "todo-api/internal/http"
+	"todo-api/internal/pkg"
+)
+
+type listRequest struct {
+	Tenant  string        `path:"tenant"`
+	Status  string        `query:"status" default:"pending" validate:"oneof=pending done"`
+	Limit   int           `query:"limit" default:"20" validate:"min=1,max=100"`
+	Tags    []string      `query:"tag"`
+	Wait    time.Duration `query:"wait"`
+	Since   *time.Time    `query:"since"`
+	IfMatch string        `header:"If-Match"`
+}
+
+type createRequest struct {
+	ID          int64   `path:"id" validate:"min=1"`
+	Title       string  `json:"title" validate:"trim,required,max=5"`
+	Description *string `json:"description" validate:"min=1"`
+	Priority    int     `json:"priority" default:"3"`
+}
+
+func request(method, target, body string, params map[string]string) *http.Request {
+	var r *http.Request
+	if body == "" {
+		r = httptest.NewRequest(method, target, nil)
+	} else {
+		r = httptest.NewRequest(method, target, strings.NewReader(body))
+	}
+	return pkg.WithScope(r, &pkg.Scope{Params: params})
+}
+
+func bindError(t *testing.T, err error, status int) *Error {
+	t.Helper()
+
+	var be *Error
+	if !errors.As(err, &be) {
+		t.Fatalf("Bind() err = %v, want *Error", err)
+	}
+	if be.Status != status {
+		t.Fatalf("Bind() status = %d, want %d; err = %v", be.Status, status, be)
+	}
+	return be
+}
+
+func TestBind_Sources(t *testing.T) {
+	r := request("GET", "/?status=done&limit=5&tag=a&tag=b&wait=2s&since=2026-01-02T03:04:05Z", "", map[string]string{"tenant": "acme"})
+	r.Header.Set("If-Match", `"v1"`)
+
+	var in listRequest
+	if err := Bind(r, &in); err != nil {
+		t.Fatalf("Bind() err = %v", err)
+	}
+	if in.Tenant != "acme" || in.Status != "done" || in.Limit != 5 || in.Wait != 2*time.Second || in.IfMatch != `"v1"` {
+		t.Fatalf("Bind() = %+v", in)
+	}
+	if !slices.Equal(in.Tags, []string{"a", "b"}) {
+		t.Fatalf("Tags = %v, want [a b]", in.Tags)
+	}
+	if in.Since == nil || !in.Since.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("Since = %v", in.Since)
+	}
+}
+
+func TestBind_Defaults(t *testing.T) {
+	var in listRequest
+	if err := Bind(request("GET", "/?limit=", "", nil), &in); err != nil {
+		t.Fatalf("Bind() err = %v", err)
+	}
+	if in.Status != "pending" || in.Limit != 20 || in.Since != nil || in.Tags != nil {
+		t.Fatalf("Bind() = %+v", in)
+	}
+}
+
+func TestBind_ConversionError_400(t *testing.T) {
+	var in listRequest
+	err := Bind(request("GET", "/?limit=ten&wait=soon&since=yesterday", "", nil), &in)
+	be := bindError(t, err, http.StatusBadRequest)
+
+	want := []httpx.FieldError{
+		{Field: "limit", Source: Query, Message: "must be an integer"},
+		{Field: "wait", Source: Query, Message: "must be a duration"},
+		{Field: "since", Source: Query, Message: "must be an RFC 3339 time"},
+	}
+	if !slices.Equal(be.Fields, want) {
+		t.Fatalf("Fields = %+v, want %+v", be.Fields, want)
+	}
+}
+
+func TestBind_Validation_422(t *testing.T) {
+	var in listRequest
+	err := Bind(request("GET", "/?status=lost&limit=500", "", nil), &in)
+	be := bindError(t, err, http.StatusUnprocessableEntity)
+
+	want := []httpx.FieldError{
+		{Field: "status", Source: Query, Message: "must be one of pending, done"},
+		{Field: "limit", Source: Query, Message: "must be at most 100"},
+	}
+	if !slices.Equal(be.Fields, want) {
+		t.Fatalf("Fields = %+v, want %+v", be.Fields, want)
+	}
+}
+
+func TestBind_Body(t *testing.T) {
+	var in createRequest
+	r := request("POST", "/", `{"title":"  milk "}`, map[string]string{"id": "7"})
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if err := Bind(r, &in); err != nil {
+		t.Fatalf("Bind() err = %v", err)
+	}
+	if in.ID != 7 || in.Title != "milk" || in.Description != nil || in.Priority != 3 {
+		t.Fatalf("Bind() = %+v", in)
+	}
+}
+
+func TestBind_BodyCantSetOtherSources(t *testing.T) {
+	var in createRequest
+	err := Bind(request("POST", "/", `{"title":"milk","ID":1}`, map[string]string{"id": "7"}), &in)
+	be := bindError(t, err, http.StatusBadRequest)
+	if want := []httpx.FieldError{{Field: "ID", Source: Body, Message: "is unknown"}}; !slices.Equal(be.Fields, want) {
+		t.Fatalf("Fields = %+v, want %+v", be.Fields, want)
+	}
+}
+
+func TestBind_BodyErrors(t *testing.T) {
+	tests := []struct {
+		name, contentType, body string
+		status                  int
+		code                    string
+	}{
+		{"wrong type", "text/plain", `{"title":"milk"}`, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		{"empty", "application/json", "", http.StatusBadRequest, "invalid_json"},
+		{"malformed", "application/json", `{"title":`, http.StatusBadRequest, "invalid_json"},
+		{"field type", "application/json", `{"title":1}`, http.StatusBadRequest, "invalid_json"},
+		{"trailing", "application/json", `{"title":"milk"}{}`, http.StatusBadRequest, "invalid_json"},
+		{"too large", "application/json", `{"title":"` + strings.Repeat("a", 100) + `"}`, http.StatusRequestEntityTooLarge, "body_too_large"},
+		{"blank title", "application/json", `{"title":"   "}`, http.StatusUnprocessableEntity, "invalid_content"},
+		{"empty description", "", `{"title":"milk","description":""}`, http.StatusUnprocessableEntity, "invalid_content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := request("POST", "/", tt.body, map[string]string{"id": "7"})
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			r.Body = http.MaxBytesReader(httptest.NewRecorder(), r.Body, 64)
+
+			var in createRequest
+			be := bindError(t, Bind(r, &in), tt.status)
+			if be.Code != tt.code {
+				t.Fatalf("Code = %q, want %q", be.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	var in createRequest
+	err := Bind(request("POST", "/", `{"title":""}`, map[string]string{"id": "0"}), &in)
+
+	rr := httptest.NewRecorder()
+	WriteError(rr, err)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rr.Code)
+	}
+	var resp httpx.ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Decode() err = %v", err)
+	}
+	want := []httpx.FieldError{
+		{Field: "id", Source: Path, Message: "must be at least 1"},
+		{Field: "title", Source: Body, Message: "is required"},
+	}
+	if resp.Error != "invalid_content" || !slices.Equal(resp.Fields, want) {
+		t.Fatalf("response = %+v", resp)
+	}
+}
+
+func TestBind_InvalidRulePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Bind() did not panic")
+		}
+	}()
+	var in struct {
+		Done bool `query:"done" validate:"max=1"`
+	}
+	_ = Bind(request("GET", "/", "", nil), &in)
+}
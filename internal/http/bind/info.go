@@ -0,0 +1,128 @@
+package bind
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// structInfo is what Bind needs to know about a struct type.
+type structInfo struct {
+	fields []*fieldInfo
+	// body is a struct type of the body fields alone, nil without any.
+	body       reflect.Type
+	bodyFields []*fieldInfo
+}
+
+type fieldInfo struct {
+	index  int
+	source string
+	name   string
+	def    []string // nil without default tag
+	rules  []rule
+}
+
+type rule struct {
+	name, arg string
+	num       float64 // arg of min and max
+}
+
+var structs sync.Map // reflect.Type -> *structInfo
+
+func structOf(t reflect.Type) *structInfo {
+	if s, ok := structs.Load(t); ok {
+		return s.(*structInfo)
+	}
+	s, _ := structs.LoadOrStore(t, parseStruct(t))
+	return s.(*structInfo)
+}
+
+func parseStruct(t reflect.Type) *structInfo {
+	s := &structInfo{}
+	var body []reflect.StructField
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		f := &fieldInfo{index: i}
+		for _, src := range []string{Path, Query, Header} {
+			if name, ok := sf.Tag.Lookup(src); ok {
+				if f.source != "" {
+					panic(fmt.Sprintf("bind: %s.%s has more than one source", t, sf.Name))
+				}
+				f.source, f.name = src, name
+			}
+		}
+		if f.source == "" {
+			name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = sf.Name
+			}
+			f.source, f.name = Body, name
+			s.bodyFields = append(s.bodyFields, f)
+			body = append(body, reflect.StructField{Name: sf.Name, Type: sf.Type, Tag: sf.Tag})
+		}
+		if def, ok := sf.Tag.Lookup("default"); ok {
+			f.def = []string{def}
+		}
+		f.rules = parseRules(t, sf)
+		s.fields = append(s.fields, f)
+	}
+	if body != nil {
+		s.body = reflect.StructOf(body)
+	}
+	return s
+}
+
+func parseRules(t reflect.Type, sf reflect.StructField) []rule {
+	tag := sf.Tag.Get("validate")
+	if tag == "" {
+		return nil
+	}
+	elem := sf.Type
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+
+	var rules []rule
+	for _, part := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(part, "=")
+		r := rule{name: name, arg: arg}
+		bad := false
+		switch name {
+		case "required":
+		case "trim":
+			bad = elem.Kind() != reflect.String
+		case "min", "max":
+			var err error
+			r.num, err = strconv.ParseFloat(arg, 64)
+			bad = err != nil || !measurable(elem)
+		case "oneof":
+			bad = strings.TrimSpace(arg) == ""
+		default:
+			bad = true
+		}
+		if bad {
+			panic(fmt.Sprintf("bind: %s.%s: invalid rule %q", t, sf.Name, part))
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+func measurable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -6,15 +6,29 @@ import (
 )
 
 type ErrorResponse struct {
-	Error   string `json:"error"`
+	Error   string       `json:"error"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError describes what is wrong with one input of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Source  string `json:"source"` // path, query, header or body
 	Message string `json:"message"`
 }
 
 func WriteError(w http.ResponseWriter, code int, errCode, msg string) {
+	WriteFieldErrors(w, code, errCode, msg, nil)
+}
+
+// WriteFieldErrors is WriteError with details per input.
+func WriteFieldErrors(w http.ResponseWriter, code int, errCode, msg string, fields []FieldError) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(ErrorResponse{
-		errCode,
-		msg,
+		Error:   errCode,
+		Message: msg,
+		Fields:  fields,
 	})
 }
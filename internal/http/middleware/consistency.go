@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"todo-api/internal/pkg"
+)
+
+// ConsistencyHeader carries the read-your-writes token: the time in unix
+// milliseconds until which the client's reads go to the primary.
+const ConsistencyHeader = "X-Consistency-Token"
+
+// ReadYourWrites hands out a token after every successful mutation and routes
+// the reads of requests presenting a valid token to the primary for window.
+func ReadYourWrites(window time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now()
+			if until, err := strconv.ParseInt(r.Header.Get(ConsistencyHeader), 10, 64); err == nil {
+				// tokens from the future are forged, ignore them
+				if t := time.UnixMilli(until); t.After(now) && !t.After(now.Add(window)) {
+					r = r.WithContext(pkg.WithReadPrimary(r.Context()))
+				}
+			}
+
+			if !isMutation(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(&tokenWriter{ResponseWriter: w, window: window}, r)
+		})
+	}
+}
+
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// tokenWriter sets the token on successful responses before the header is sent.
+type tokenWriter struct {
+	http.ResponseWriter
+	window      time.Duration
+	wroteHeader bool
+}
+
+func (w *tokenWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if code < 400 {
+			until := time.Now().Add(w.window).UnixMilli()
+			w.Header().Set(ConsistencyHeader, strconv.FormatInt(until, 10))
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *tokenWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"todo-api/internal/pkg"
+)
+
+func TestReadYourWrites_TokenAfterMutation(t *testing.T) {
+	h := ReadYourWrites(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/todos", nil))
+
+	until, err := strconv.ParseInt(rec.Header().Get(ConsistencyHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("token = %q, want unix millis", rec.Header().Get(ConsistencyHeader))
+	}
+	if d := time.Until(time.UnixMilli(until)); d <= 0 || d > time.Minute {
+		t.Fatalf("token expires in %v, want within a minute", d)
+	}
+}
+
+func TestReadYourWrites_NoTokenOnFailureOrRead(t *testing.T) {
+	for _, tc := range []struct {
+		method string
+		code   int
+	}{
+		{http.MethodPost, http.StatusBadRequest},
+		{http.MethodGet, http.StatusOK},
+	} {
+		h := ReadYourWrites(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.code)
+		}))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tc.method, "/todos", nil))
+		if tok := rec.Header().Get(ConsistencyHeader); tok != "" {
+			t.Fatalf("%s -> %d: unexpected token %q", tc.method, tc.code, tok)
+		}
+	}
+}
+
+func TestReadYourWrites_TokenRoutesToPrimary(t *testing.T) {
+	var primary bool
+	h := ReadYourWrites(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primary = pkg.ReadPrimary(r.Context())
+	}))
+
+	for _, tc := range []struct {
+		name  string
+		token time.Time
+		want  bool
+	}{
+		{"valid", time.Now().Add(30 * time.Second), true},
+		{"expired", time.Now().Add(-time.Second), false},
+		{"forged", time.Now().Add(time.Hour), false},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/todos/1", nil)
+		req.Header.Set(ConsistencyHeader, strconv.FormatInt(tc.token.UnixMilli(), 10))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if primary != tc.want {
+			t.Fatalf("%s: ReadPrimary = %v, want %v", tc.name, primary, tc.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CORSOptions struct {
+	// AllowedOrigins are origins like "https://app.example.com", a "*" stands
+	// for any text, so "https://*.example.com" allows all subdomains and "*"
+	// every origin.
+	AllowedOrigins []string
+	// AllowedHeaders may be sent by the client, "*" allows whatever the
+	// preflight asks for.
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight, 0 leaves it to them.
+	MaxAge time.Duration
+}
+
+// Validate rejects credentials for origin patterns matching any domain, such
+// as "*" or "https://*", which would let every site make requests with the
+// user's cookies.
+func (o CORSOptions) Validate() error {
+	if !o.AllowCredentials {
+		return nil
+	}
+	for _, p := range o.AllowedOrigins {
+		if wildcardHost(p) {
+			return fmt.Errorf("cors: credentials cannot be allowed for origin %q, it matches any domain", p)
+		}
+	}
+	return nil
+}
+
+// wildcardHost reports whether the host of the origin pattern p has a
+// wildcard other than the first label of a domain, as in *.example.com.
+func wildcardHost(p string) bool {
+	_, host, ok := strings.Cut(p, "://")
+	if !ok {
+		host = p
+	}
+	if i := strings.LastIndexByte(host, ':'); i >= 0 {
+		host = host[:i] // port
+	}
+	if !strings.Contains(host, "*") {
+		return false
+	}
+	domain, ok := strings.CutPrefix(host, "*.")
+	return !ok || strings.Contains(domain, "*") || !strings.Contains(domain, ".")
+}
+
+// CORS lets browsers call the API from the allowed origins. It belongs in
+// front of the router: the router answers preflight requests as any other
+// OPTIONS request, and CORS grants the methods of its Allow header. Requests
+// from other origins pass without CORS headers, so browsers block them.
+// It panics if opts are invalid, see Validate.
+func CORS(opts CORSOptions) Middleware {
+	if err := opts.Validate(); err != nil {
+		panic(err.Error())
+	}
+	allowHeaders := strings.Join(opts.AllowedHeaders, ", ")
+	anyHeader := slices.Contains(opts.AllowedHeaders, "*")
+	exposed := strings.Join(opts.ExposedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if !originAllowed(opts.AllowedOrigins, origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if opts.AllowedOrigins[0] == "*" && len(opts.AllowedOrigins) == 1 {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if opts.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			method := r.Header.Get("Access-Control-Request-Method")
+			if r.Method != http.MethodOptions || method == "" {
+				if exposed != "" {
+					h.Set("Access-Control-Expose-Headers", exposed)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// preflight
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			headers := allowHeaders
+			if anyHeader {
+				headers = r.Header.Get("Access-Control-Request-Headers")
+			}
+			next.ServeHTTP(&preflightWriter{ResponseWriter: w, method: method, headers: headers, maxAge: opts.MaxAge}, r)
+		})
+	}
+}
+
+func originAllowed(patterns []string, origin string) bool {
+	for _, p := range patterns {
+		prefix, suffix, wild := strings.Cut(p, "*")
+		if !wild {
+			if strings.EqualFold(p, origin) {
+				return true
+			}
+			continue
+		}
+		if len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// preflightWriter adds the preflight grant to the router's OPTIONS answer
+// when its Allow header has the requested method.
+type preflightWriter struct {
+	http.ResponseWriter
+	method, headers string
+	maxAge          time.Duration
+	wroteHeader     bool
+}
+
+func (w *preflightWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		h := w.Header()
+		allow := strings.Split(h.Get("Allow"), ", ")
+		if code < 300 && slices.Contains(allow, w.method) {
+			h.Set("Access-Control-Allow-Methods", strings.Join(allow, ", "))
+			if w.headers != "" {
+				h.Set("Access-Control-Allow-Headers", w.headers)
+			}
+			if w.maxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(w.maxAge.Seconds())))
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *preflightWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
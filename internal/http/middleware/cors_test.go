@@ -0,0 +1,124 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo-api/internal/http/middleware"
+	"todo-api/internal/http/router"
+)
+
+func corsRouter(opts middleware.CORSOptions) http.Handler {
+	r := &router.Router{}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Consistency-Token", "1")
+	})
+	r.Handle(http.MethodGet, "/todos/:id", ok)
+	r.Handle(http.MethodDelete, "/todos/:id", ok)
+	return middleware.CORS(opts)(r)
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	h := corsRouter(middleware.CORSOptions{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedHeaders:   []string{"Content-Type", "X-Tenant-ID"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	for _, tc := range []struct {
+		name, origin, method, path string
+		code                       int
+		methods                    string
+	}{
+		{"allowed", "https://app.example.com", "DELETE", "/todos/1", http.StatusNoContent, "GET, DELETE, HEAD, OPTIONS"},
+		{"method not registered", "https://app.example.com", "PUT", "/todos/1", http.StatusNoContent, ""},
+		{"other origin", "https://evil.com", "DELETE", "/todos/1", http.StatusNoContent, ""},
+		{"bare domain", "https://example.com", "DELETE", "/todos/1", http.StatusNoContent, ""},
+		{"unknown path", "https://app.example.com", "DELETE", "/nope", http.StatusNotFound, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tc.path, nil)
+			req.Header.Set("Origin", tc.origin)
+			req.Header.Set("Access-Control-Request-Method", tc.method)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.code {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.code)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Methods"); got != tc.methods {
+				t.Fatalf("Allow-Methods = %q, want %q", got, tc.methods)
+			}
+			if tc.methods == "" {
+				return
+			}
+			want := map[string]string{
+				"Access-Control-Allow-Origin":      tc.origin,
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Headers":     "Content-Type, X-Tenant-ID",
+				"Access-Control-Max-Age":           "600",
+			}
+			for k, v := range want {
+				if got := rec.Header().Get(k); got != v {
+					t.Fatalf("%s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestCORS_Request(t *testing.T) {
+	h := corsRouter(middleware.CORSOptions{
+		AllowedOrigins: []string{"*"},
+		ExposedHeaders: []string{"X-Consistency-Token"},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/todos/1", nil)
+	req.Header.Set("Origin", "https://anywhere.org")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("Allow-Origin = %q, want *", got)
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); got != "X-Consistency-Token" {
+		t.Fatalf("Expose-Headers = %q", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Origin" {
+		t.Fatalf("Vary = %q, want Origin", got)
+	}
+}
+
+func TestCORS_CredentialsForAnyOrigin(t *testing.T) {
+	for _, tc := range []struct {
+		origin string
+		ok     bool
+	}{
+		{"https://app.example.com", true},
+		{"https://*.example.com", true},
+		{"https://*.example.com:8443", true},
+		{"*", false},
+		{"https://*", false},
+		{"*://*", false},
+		{"https://*:8443", false},
+		{"https://app*", false},
+		{"https://*.com", false},
+		{"https://*.*.example.com", false},
+	} {
+		opts := middleware.CORSOptions{AllowedOrigins: []string{tc.origin}, AllowCredentials: true}
+		if err := opts.Validate(); (err == nil) != tc.ok {
+			t.Fatalf("Validate(%q) err = %v, want ok = %v", tc.origin, err, tc.ok)
+		}
+	}
+
+	opts := middleware.CORSOptions{AllowedOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true}
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("CORS() did not panic")
+		}
+	}()
+	middleware.CORS(opts)
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type DeprecationOptions struct {
+	// Since is when the routes were deprecated, zero sends "true".
+	Since time.Time
+	// Sunset is when the routes go away, zero leaves it open.
+	Sunset time.Time
+	// Link points to documentation of the deprecation, such as a migration
+	// guide, "" for none.
+	Link string
+}
+
+// Deprecation marks the responses of deprecated routes with the Deprecation
+// header of RFC 9745 and the Sunset header of RFC 8594, so clients can see
+// they have to move on.
+func Deprecation(opts DeprecationOptions) Middleware {
+	deprecation := "true"
+	if !opts.Since.IsZero() {
+		deprecation = "@" + strconv.FormatInt(opts.Since.Unix(), 10)
+	}
+	var sunset string
+	if !opts.Sunset.IsZero() {
+		sunset = opts.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", deprecation)
+			if sunset != "" {
+				h.Set("Sunset", sunset)
+			}
+			if opts.Link != "" {
+				h.Add("Link", "<"+opts.Link+`>; rel="deprecation"`)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
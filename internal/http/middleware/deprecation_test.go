@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeprecation(t *testing.T) {
+	h := Deprecation(DeprecationOptions{
+		Since:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sunset: time.Date(2026, 7, 1, 0, 0, 0, 0, time.FixedZone("CEST", 2*3600)),
+		Link:   "https://example.com/docs/v2",
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/todos/1", nil))
+
+	want := map[string]string{
+		"Deprecation": "@1767225600",
+		"Sunset":      "Tue, 30 Jun 2026 22:00:00 GMT",
+		"Link":        `<https://example.com/docs/v2>; rel="deprecation"`,
+	}
+	for k, v := range want {
+		if got := rec.Header().Get(k); got != v {
+			t.Fatalf("%s = %q, want %q", k, got, v)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	Deprecation(DeprecationOptions{})(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Header().Get("Deprecation") != "true" || rec.Header().Get("Sunset") != "" {
+		t.Fatalf("header = %v, want only Deprecation: true", rec.Header())
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"todo-api/internal/pkg"
+)
+
+// RouteLimits enforces the body limit and timeout a route declares with the
+// router's BodyLimit and Timeout options. Reading a body past the limit fails
+// with *http.MaxBytesError, and the request context is cancelled once the
+// timeout passes, so handlers and storage give up on their own. It must run
+// behind the router, which puts the route in the scope.
+func RouteLimits() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := pkg.RouteFrom(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if route.BodyLimit > 0 && r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, route.BodyLimit)
+			}
+			if route.Timeout > 0 {
+				ctx, cancel := context.WithTimeout(r.Context(), route.Timeout)
+				defer cancel()
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
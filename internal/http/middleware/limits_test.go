@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"todo-api/internal/pkg"
+)
+
+func TestRouteLimits(t *testing.T) {
+	var readErr error
+	var deadline time.Time
+	h := RouteLimits()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+		deadline, _ = r.Context().Deadline()
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/todos", strings.NewReader(strings.Repeat("a", 100)))
+	req = pkg.WithScope(req, &pkg.Scope{Route: &pkg.RouteMeta{BodyLimit: 10, Timeout: time.Minute}})
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var tooLarge *http.MaxBytesError
+	if !errors.As(readErr, &tooLarge) {
+		t.Fatalf("ReadAll() err = %v, want *http.MaxBytesError", readErr)
+	}
+	if deadline.IsZero() || time.Until(deadline) > time.Minute {
+		t.Fatalf("deadline = %v, want within a minute", deadline)
+	}
+
+	// no route, no limits
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/todos", strings.NewReader(strings.Repeat("a", 100))))
+	if readErr != nil || !deadline.IsZero() {
+		t.Fatalf("err = %v, deadline = %v, want none", readErr, deadline)
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	httpx "todo-api/internal/http"
+	"todo-api/internal/pkg"
+)
+
+// TenantHeader is the usual header naming the tenant of a request.
+// It must be set or stripped by a trusted proxy, clients can choose any value.
+const TenantHeader = "X-Tenant-ID"
+
+// TenantResolver returns the tenant a request belongs to, "" if it cannot tell.
+// Resolvers based on authentication, e.g. a token claim, fit the same type.
+type TenantResolver func(r *http.Request) string
+
+// TenantFromHeader takes the tenant from the header name, which clients
+// control. It belongs after the resolvers of Tenant that can be trusted.
+func TenantFromHeader(name string) TenantResolver {
+	return func(r *http.Request) string {
+		return strings.ToLower(strings.TrimSpace(r.Header.Get(name)))
+	}
+}
+
+// TenantFromSubdomain takes the tenant from the label in front of domain,
+// e.g. "acme" for acme.todo.example.com and domain todo.example.com.
+func TenantFromSubdomain(domain string) TenantResolver {
+	suffix := "." + strings.ToLower(strings.Trim(domain, "."))
+	return func(r *http.Request) string {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.ToLower(host)
+		if !strings.HasSuffix(host, suffix) {
+			return ""
+		}
+		sub := strings.TrimSuffix(host, suffix)
+		if strings.Contains(sub, ".") {
+			return ""
+		}
+		return sub
+	}
+}
+
+// Tenant stores the tenant of every request in its context and scope, taken
+// from the first resolver that finds one, so resolvers go from the most to
+// the least trusted: authentication and subdomains before headers. Requests
+// without a tenant get pkg.DefaultTenant, or a 400 when required is set.
+func Tenant(required bool, resolvers ...TenantResolver) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant := ""
+			for _, resolve := range resolvers {
+				if tenant = resolve(r); tenant != "" {
+					break
+				}
+			}
+
+			switch {
+			case tenant == "" && required:
+				httpx.WriteError(w, http.StatusBadRequest, "tenant_required", "tenant required")
+				return
+			case tenant == "":
+				tenant = pkg.DefaultTenant
+			case !pkg.ValidTenant(tenant):
+				httpx.WriteError(w, http.StatusBadRequest, "invalid_tenant", "invalid tenant")
+				return
+			}
+
+			if scope := pkg.ScopeFrom(r); scope != nil {
+				scope.Tenant = tenant
+			}
+			next.ServeHTTP(w, r.WithContext(pkg.WithTenant(r.Context(), tenant)))
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-api/internal/pkg"
+)
+
+func TestTenant_Resolve(t *testing.T) {
+	mw := Tenant(false, TenantFromSubdomain("todo.example.com"), TenantFromHeader(TenantHeader))
+
+	for _, tc := range []struct {
+		name   string
+		host   string
+		header string
+		want   string
+		code   int
+	}{
+		{"header", "localhost", "Acme", "acme", http.StatusOK},
+		{"subdomain wins", "other.todo.example.com", "acme", "other", http.StatusOK},
+		{"subdomain", "acme.todo.example.com:8080", "", "acme", http.StatusOK},
+		{"nested subdomain", "a.b.todo.example.com", "", pkg.DefaultTenant, http.StatusOK},
+		{"none", "todo.example.com", "", pkg.DefaultTenant, http.StatusOK},
+		{"invalid", "localhost", "../etc", "", http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got, scoped string
+			h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = pkg.TenantFrom(r.Context())
+				scoped = pkg.ScopeFrom(r).Tenant
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/todos/1", nil)
+			req.Host = tc.host
+			if tc.header != "" {
+				req.Header.Set(TenantHeader, tc.header)
+			}
+			req = pkg.WithScope(req, &pkg.Scope{})
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.code {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.code)
+			}
+			if got != tc.want || (tc.want != "" && scoped != tc.want) {
+				t.Fatalf("tenant = %q, scope tenant = %q, want %q", got, scoped, tc.want)
+			}
+		})
+	}
+}
+
+func TestTenant_Required(t *testing.T) {
+	called := false
+	h := Tenant(true, TenantFromHeader(TenantHeader))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/todos/1", nil))
+	if rec.Code != http.StatusBadRequest || called {
+		t.Fatalf("status = %d, called = %v, want 400 without calling the handler", rec.Code, called)
+	}
+}
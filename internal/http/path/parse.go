@@ -0,0 +1,162 @@
+package path
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Segment is one element of a parsed pattern.
+//
+//	literal      matches itself
+//	:name        matches any non-empty segment
+//	:name<int>   only values of a registered type
+//	:name<[a-z]+> only values matching the regular expression
+//	:name?       optional, only at the end of a pattern
+//	*name        the rest of the path, one or more segments; last in a pattern
+//
+// Constraints can't contain a slash.
+type Segment struct {
+	Param    bool
+	Wildcard bool
+	Optional bool
+	Value    string // literal text or parameter name
+	// Constraint is the type name or expression between angle brackets,
+	// Check tests a value against it. Both are empty without constraint.
+	Constraint string
+	Check      func(string) bool
+}
+
+var (
+	typesMu sync.RWMutex
+	types   = map[string]func(string) bool{
+		"int":   regexp.MustCompile(`^-?[0-9]+$`).MatchString,
+		"uint":  regexp.MustCompile(`^[0-9]+$`).MatchString,
+		"alpha": regexp.MustCompile(`^[A-Za-z]+$`).MatchString,
+		"uuid":  regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString,
+	}
+	identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// RegisterType makes name usable as a constraint, :id<name> only matches
+// values for which fn returns true. It panics if name is not an identifier
+// or already registered.
+func RegisterType(name string, fn func(string) bool) {
+	typesMu.Lock()
+	defer typesMu.Unlock()
+
+	if !identRe.MatchString(name) {
+		panic("path: invalid type name " + name)
+	}
+	if _, dup := types[name]; dup {
+		panic("path: type " + name + " registered twice")
+	}
+	types[name] = fn
+}
+
+func lookupType(name string) (func(string) bool, bool) {
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	fn, ok := types[name]
+	return fn, ok
+}
+
+// Parse splits pattern into its segments, "/" has none. It applies the same
+// rules to the pattern as Match does.
+func Parse(pattern string) ([]Segment, error) {
+	if len(pattern) == 0 || pattern[0] != '/' {
+		return nil, ErrInvalidPattern
+	}
+	if pattern == "/" {
+		return nil, nil
+	}
+	if pattern[len(pattern)-1] == '/' {
+		return nil, ErrInvalidPattern
+	}
+
+	parts := strings.Split(pattern[1:], "/")
+	segs := make([]Segment, 0, len(parts))
+	seen := make(map[string]bool)
+	for i, part := range parts {
+		if len(part) == 0 {
+			return nil, ErrInvalidPattern
+		}
+		if part[0] != ':' && part[0] != '*' {
+			if len(segs) > 0 && segs[len(segs)-1].Optional {
+				return nil, fmt.Errorf("%w: %q follows an optional parameter", ErrInvalidPattern, part)
+			}
+			segs = append(segs, Segment{Value: part})
+			continue
+		}
+
+		seg, err := parseParam(part)
+		if err != nil {
+			return nil, err
+		}
+		if seen[seg.Value] {
+			return nil, ErrDuplicateParam
+		}
+		seen[seg.Value] = true
+
+		if seg.Wildcard && i != len(parts)-1 {
+			return nil, fmt.Errorf("%w: %q is not last", ErrInvalidPattern, part)
+		}
+		if len(segs) > 0 && segs[len(segs)-1].Optional && !seg.Optional {
+			return nil, fmt.Errorf("%w: %q follows an optional parameter", ErrInvalidPattern, part)
+		}
+		segs = append(segs, seg)
+	}
+	return segs, nil
+}
+
+// parseParam parses a :param or *wildcard segment.
+func parseParam(part string) (Segment, error) {
+	seg := Segment{Param: part[0] == ':', Wildcard: part[0] == '*'}
+	name := part[1:]
+
+	if strings.HasSuffix(name, "?") {
+		if seg.Wildcard {
+			return Segment{}, fmt.Errorf("%w: wildcard %q can't be optional", ErrInvalidPattern, part)
+		}
+		seg.Optional = true
+		name = name[:len(name)-1]
+	}
+	if i := strings.IndexByte(name, '<'); i >= 0 {
+		if !strings.HasSuffix(name, ">") {
+			return Segment{}, fmt.Errorf("%w: unterminated constraint in %q", ErrInvalidPattern, part)
+		}
+		seg.Constraint = name[i+1 : len(name)-1]
+		name = name[:i]
+
+		check, err := compileConstraint(seg.Constraint)
+		if err != nil {
+			return Segment{}, fmt.Errorf("%w: %q: %v", ErrInvalidPattern, part, err)
+		}
+		seg.Check = check
+	}
+	if name == "" || strings.ContainsAny(name, "<>?*:") {
+		return Segment{}, fmt.Errorf("%w: bad parameter name in %q", ErrInvalidPattern, part)
+	}
+	seg.Value = name
+	return seg, nil
+}
+
+// compileConstraint returns the check of a registered type or, if c is not
+// an identifier, of the regular expression c matching the whole value.
+func compileConstraint(c string) (func(string) bool, error) {
+	if c == "" {
+		return nil, fmt.Errorf("empty constraint")
+	}
+	if identRe.MatchString(c) {
+		if fn, ok := lookupType(c); ok {
+			return fn, nil
+		}
+		return nil, fmt.Errorf("unknown type %q", c)
+	}
+	re, err := regexp.Compile(`^(?:` + c + `)$`)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
@@ -15,18 +15,13 @@ var (
 )
 
 // MatchPath("/todos/:id", "/todos/123") => ok=true, {"id":"123"}
+//
+// A path with a trailing slash never matches, it is ErrInvalidPath if it
+// would match without the slash.
 func Match(pattern, path string) (ok bool, params map[string]string, err error) {
-
-	if len(pattern) == 0 {
-		return false, nil, ErrInvalidPattern
-	}
-
-	if pattern[0] != '/' {
-		return false, nil, ErrInvalidPattern
-	}
-
-	if pattern[len(pattern)-1] == '/' && len(pattern) != 1 {
-		return false, nil, ErrInvalidPattern
+	segs, err := Parse(pattern)
+	if err != nil {
+		return false, nil, err
 	}
 
 	if len(path) == 0 {
@@ -38,47 +33,68 @@ func Match(pattern, path string) (ok bool, params map[string]string, err error)
 	}
 
 	if path[len(path)-1] == '/' && len(path) != 1 {
-		return false, nil, ErrInvalidPath
+		// only the trailing slash keeps it from matching, callers can
+		// redirect to the path without it
+		if ok, _, err := Match(pattern, strings.TrimRight(path, "/")); ok || err != nil {
+			return false, nil, ErrInvalidPath
+		}
+		return false, nil, nil
 	}
 
-	partsPat := strings.Split(pattern, "/")
-	partsPath := strings.Split(path, "/")
-	if len(partsPat) != len(partsPath) {
-		return false, nil, nil
+	var partsPath []string
+	if path != "/" {
+		partsPath = strings.Split(path[1:], "/")
+	}
+	decoded := make([]string, len(partsPath))
+	for i, part := range partsPath {
+		if decoded[i], err = url.PathUnescape(part); err != nil {
+			return false, nil, ErrBadEncoding
+		}
 	}
 
 	params = make(map[string]string)
 
-	for i := 0; i < len(partsPath); i++ {
-
-		decoded, err := url.PathUnescape(partsPath[i])
-		if err != nil {
-			return false, nil, ErrBadEncoding
+	for i, seg := range segs {
+		if i >= len(decoded) {
+			if seg.Optional {
+				break
+			}
+			return false, nil, nil
 		}
 
-		// check for a part to be a parameter
-		if partPat := partsPat[i]; len(partPat) != 0 && partPat[0] == ':' {
-			key := partPat[1:]
-			if len(key) == 0 {
-				return false, nil, ErrInvalidPattern
+		switch {
+		case seg.Wildcard:
+			for _, part := range decoded[i:] {
+				if strings.Contains(part, "/") {
+					return false, nil, ErrEncodedSlash
+				}
 			}
-			_, ok = params[key]
-			if ok {
-				return false, nil, ErrDuplicateParam
+			value := strings.Join(decoded[i:], "/")
+			if seg.Check != nil && !seg.Check(value) {
+				return false, nil, nil
 			}
-			if len(decoded) == 0 {
+			params[seg.Value] = value
+			return true, params, nil
+		case seg.Param:
+			if len(decoded[i]) == 0 {
 				return false, nil, nil
 			}
-			if strings.Contains(decoded, "/") {
+			if strings.Contains(decoded[i], "/") {
 				return false, nil, ErrEncodedSlash
 			}
-			params[key] = decoded
-		} else {
-			if partsPat[i] != decoded {
+			if seg.Check != nil && !seg.Check(decoded[i]) {
+				return false, nil, nil
+			}
+			params[seg.Value] = decoded[i]
+		default:
+			if seg.Value != decoded[i] {
 				return false, nil, nil
 			}
 		}
 	}
+	if len(decoded) > len(segs) {
+		return false, nil, nil
+	}
 
 	return true, params, nil
 }
@@ -2,6 +2,8 @@ package path
 
 import (
 	"errors"
+	"maps"
+	"strings"
 	"testing"
 )
 
@@ -158,3 +160,54 @@ func TestMatch_MultipleParams(t *testing.T) {
 		t.Fatalf("params mismatch: %v", parts)
 	}
 }
+
+func TestParse_Errors(t *testing.T) {
+	for _, pattern := range []string{
+		"/files/*path/more",
+		"/files/*path?",
+		"/todos/:id?/edit",
+		"/todos/:id?/:slug",
+		"/todos/:id<nosuchtype>",
+		"/todos/:id<[a-z>",
+		"/todos/:id<(>",
+		"/todos/:<int>",
+		"/todos/:id<>",
+	} {
+		if _, err := Parse(pattern); !errors.Is(err, ErrInvalidPattern) {
+			t.Fatalf("Parse(%q) err = %v, want %v", pattern, err, ErrInvalidPattern)
+		}
+	}
+}
+
+func TestMatch_Extended(t *testing.T) {
+	RegisterType("even", func(s string) bool {
+		return len(s) > 0 && strings.ContainsAny(s[len(s)-1:], "02468")
+	})
+
+	tests := []struct {
+		pattern, path string
+		ok            bool
+		params        map[string]string
+	}{
+		{"/files/*path", "/files/a/b%20c", true, map[string]string{"path": "a/b c"}},
+		{"/files/*path", "/files", false, nil},
+		{"/todos/:id?", "/todos", true, map[string]string{}},
+		{"/todos/:id?", "/todos/7", true, map[string]string{"id": "7"}},
+		{"/todos/:id?", "/todos/7/8", false, nil},
+		{"/todos/:id<int>", "/todos/-7", true, map[string]string{"id": "-7"}},
+		{"/todos/:id<int>", "/todos/x", false, nil},
+		{"/posts/:slug<[a-z-]+>", "/posts/hello-world", true, map[string]string{"slug": "hello-world"}},
+		{"/posts/:slug<[a-z-]+>", "/posts/Hello", false, nil},
+		{"/n/:n<even>", "/n/12", true, map[string]string{"n": "12"}},
+		{"/n/:n<even>", "/n/13", false, nil},
+	}
+	for _, tt := range tests {
+		ok, params, err := Match(tt.pattern, tt.path)
+		if err != nil {
+			t.Fatalf("Match(%q, %q) err = %v", tt.pattern, tt.path, err)
+		}
+		if ok != tt.ok || tt.ok && !maps.Equal(params, tt.params) {
+			t.Fatalf("Match(%q, %q) = %v, %v, want %v, %v", tt.pattern, tt.path, ok, params, tt.ok, tt.params)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-api/internal/http/path"
+	"todo-api/internal/pkg"
+)
+
+// linearRouter is the router before the route tree, it tries every route
+// with path.Match.
+type linearRouter struct {
+	routes []Route
+}
+
+func (lr *linearRouter) Handle(method, pattern string, h http.Handler, _ ...RouteOption) error {
+	lr.routes = append(lr.routes, Route{Method: method, Pattern: pattern, Handler: h})
+	return nil
+}
+
+func (lr *linearRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var allow []string
+	for _, route := range lr.routes {
+		ok, vals, err := path.Match(route.Pattern, r.URL.EscapedPath())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if ok {
+			if r.Method != route.Method {
+				allow = append(allow, route.Method)
+				continue
+			}
+			route.Handler.ServeHTTP(w, pkg.WithScope(r, &pkg.Scope{Params: vals}))
+			return
+		}
+	}
+	if len(allow) > 0 {
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+type handleRouter interface {
+	http.Handler
+	Handle(method, pattern string, h http.Handler, opts ...RouteOption) error
+}
+
+// benchRouter registers 400 routes on r, 8 for each of 50 resources.
+func benchRouter(r handleRouter) handleRouter {
+	h := http.HandlerFunc(ok)
+	for i := range 50 {
+		base := fmt.Sprintf("/api/v1/res%d", i)
+		r.Handle(http.MethodGet, base, h)
+		r.Handle(http.MethodPost, base, h)
+		r.Handle(http.MethodGet, base+"/items", h)
+		r.Handle(http.MethodGet, base+"/:id", h)
+		r.Handle(http.MethodPut, base+"/:id", h)
+		r.Handle(http.MethodDelete, base+"/:id", h)
+		r.Handle(http.MethodGet, base+"/:id/items/:item", h)
+		r.Handle(http.MethodDelete, base+"/:id/items/:item", h)
+	}
+	return r
+}
+
+func benchServe(b *testing.B, r http.Handler, method, target string) {
+	req := httptest.NewRequest(method, target, nil)
+	w := httptest.NewRecorder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	cases := []struct{ name, method, target string }{
+		{"static", http.MethodGet, "/api/v1/res49/items"},
+		{"param", http.MethodDelete, "/api/v1/res49/7"},
+		{"params", http.MethodGet, "/api/v1/res49/7/items/3"},
+		{"not_found", http.MethodGet, "/api/v2/nope"},
+	}
+	for _, c := range cases {
+		b.Run("tree/"+c.name, func(b *testing.B) {
+			benchServe(b, benchRouter(&Router{}), c.method, c.target)
+		})
+		b.Run("linear/"+c.name, func(b *testing.B) {
+			benchServe(b, benchRouter(&linearRouter{}), c.method, c.target)
+		})
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+	root := &benchRouter(&Router{}).(*Router).t.root
+	for _, target := range []string{"/api/v1/res49/items", "/api/v1/res49/7/items/3"} {
+		b.Run(target, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				root.lookup(http.MethodGet, target, nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package router
+
+import (
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Matcher narrows a route down to some requests beyond method and path, so
+// that one path can lead to different routes, e.g. by API version. Routes
+// with matchers are tried before the route without any for the same path and
+// method, which serves the requests they turn down.
+type Matcher interface {
+	// Match reports whether r matches and returns the parameters it captured.
+	Match(r *http.Request) (map[string]string, bool)
+	// String describes the matcher, routes with the same descriptions
+	// conflict.
+	String() string
+}
+
+// varier is a Matcher depending on a request header, which the router then
+// lists in the Vary header of the response.
+type varier interface {
+	vary() string
+}
+
+// When applies the matchers to the route, in addition to those of its router.
+func When(ms ...Matcher) RouteOption {
+	return func(r *Route) {
+		r.matchers = append(r.matchers, ms...)
+	}
+}
+
+type hostMatcher struct {
+	pattern string
+	labels  []string
+}
+
+// Host matches the host of the request without port against pattern, a
+// domain name whose labels may be a parameter such as :tenant, which is
+// added to the scope, or * for any label:
+//
+//	router.Host(":tenant.todo.example.com")
+func Host(pattern string) Matcher {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	return hostMatcher{pattern: pattern, labels: strings.Split(pattern, ".")}
+}
+
+func (m hostMatcher) Match(r *http.Request) (map[string]string, bool) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	labels := strings.Split(strings.ToLower(strings.TrimSuffix(host, ".")), ".")
+	if len(labels) != len(m.labels) {
+		return nil, false
+	}
+
+	var params map[string]string
+	for i, want := range m.labels {
+		switch {
+		case want == "*":
+		case strings.HasPrefix(want, ":"):
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[want[1:]] = labels[i]
+		case want != labels[i]:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (m hostMatcher) String() string { return "host " + m.pattern }
+
+type headerMatcher struct {
+	name, value string
+}
+
+// Header matches requests with the header name, and one of its values equal
+// to value unless that is empty.
+func Header(name, value string) Matcher {
+	return headerMatcher{name: http.CanonicalHeaderKey(name), value: value}
+}
+
+func (m headerMatcher) Match(r *http.Request) (map[string]string, bool) {
+	vals := r.Header.Values(m.name)
+	if m.value == "" {
+		return nil, len(vals) > 0
+	}
+	for _, v := range vals {
+		if strings.TrimSpace(v) == m.value {
+			return nil, true
+		}
+	}
+	return nil, false
+}
+
+func (m headerMatcher) String() string {
+	if m.value == "" {
+		return "header " + m.name
+	}
+	return "header " + m.name + "=" + m.value
+}
+
+func (m headerMatcher) vary() string { return m.name }
+
+type mediaTypeMatcher struct {
+	mediaType string
+}
+
+// MediaType matches requests accepting the media type, for versioned APIs
+// one like application/vnd.todo.v2+json. Wildcards in Accept don't count,
+// they are left to the route without matchers.
+func MediaType(mediaType string) Matcher {
+	return mediaTypeMatcher{mediaType: strings.ToLower(mediaType)}
+}
+
+func (m mediaTypeMatcher) Match(r *http.Request) (map[string]string, bool) {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mt, params, err := mime.ParseMediaType(part)
+			if err != nil || mt != m.mediaType {
+				continue
+			}
+			if q := strings.TrimSpace(params["q"]); q != "" && strings.Trim(q, "0.") == "" {
+				continue // q=0 refuses the type
+			}
+			return nil, true
+		}
+	}
+	return nil, false
+}
+
+func (m mediaTypeMatcher) String() string { return "accept " + m.mediaType }
+
+func (m mediaTypeMatcher) vary() string { return "Accept" }
+
+// matchKey identifies the matchers of a route for conflict detection.
+func matchKey(ms []Matcher) string {
+	keys := make([]string, len(ms))
+	for i, m := range ms {
+		keys[i] = m.String()
+	}
+	slices.Sort(keys)
+	return strings.Join(keys, "\n")
+}
@@ -0,0 +1,120 @@
+package router
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-api/internal/http/middleware"
+	"todo-api/internal/pkg"
+)
+
+func say(s string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, s+" "+pkg.ScopeFrom(r).Params["tenant"])
+	})
+}
+
+func versionedRouter() *Router {
+	r := &Router{}
+	r.Group("/api", func(api *Router) {
+		api.Group("", func(v1 *Router) {
+			v1.Use(middleware.Deprecation(middleware.DeprecationOptions{}))
+			v1.Handle(http.MethodGet, "/todos/:id", say("v1"))
+		})
+		api.Group("", func(v2 *Router) {
+			v2.Match(MediaType("application/vnd.todo.v2+json"))
+			v2.Handle(http.MethodGet, "/todos/:id", say("v2"))
+			v2.Handle(http.MethodPost, "/todos", say("v2"))
+		})
+	})
+	r.Handle(http.MethodGet, "/whoami", say("tenant"), When(Host(":tenant.todo.example.com")))
+	r.Handle(http.MethodGet, "/whoami", say("admin"), When(Host("admin.*.example.com"), Header("X-Admin", "")))
+	r.Handle(http.MethodGet, "/whoami", say("nobody"))
+	return r
+}
+
+func TestRouter_MediaTypeVersions(t *testing.T) {
+	r := versionedRouter()
+
+	for _, tc := range []struct {
+		accept, want string
+		deprecated   bool
+	}{
+		{"", "v1 ", true},
+		{"application/json", "v1 ", true},
+		{"application/vnd.todo.v2+json", "v2 ", false},
+		{"text/html, application/vnd.todo.v2+json; q=0.9", "v2 ", false},
+		{"application/vnd.todo.v2+json; q=0", "v1 ", true},
+		{"*/*", "v1 ", true},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/todos/1", nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Body.String() != tc.want {
+			t.Fatalf("Accept %q: body = %q, want %q", tc.accept, rec.Body.String(), tc.want)
+		}
+		if got := rec.Header().Get("Deprecation") != ""; got != tc.deprecated {
+			t.Fatalf("Accept %q: deprecated = %v, want %v", tc.accept, got, tc.deprecated)
+		}
+		if rec.Header().Get("Vary") != "Accept" {
+			t.Fatalf("Accept %q: Vary = %q, want Accept", tc.accept, rec.Header().Get("Vary"))
+		}
+	}
+
+	// POST /api/todos only exists in v2
+	req := httptest.NewRequest(http.MethodPost, "/api/todos", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("v1 POST status = %d, want 404", rec.Code)
+	}
+	req.Header.Set("Accept", "application/vnd.todo.v2+json")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("v2 POST status = %d, want 200", rec.Code)
+	}
+}
+
+func TestRouter_HostAndHeader(t *testing.T) {
+	r := versionedRouter()
+
+	for _, tc := range []struct {
+		host, admin, want string
+	}{
+		{"acme.todo.example.com", "", "tenant acme"},
+		{"Globex.todo.example.com:8080", "", "tenant globex"},
+		{"admin.eu.example.com", "1", "admin "},
+		{"admin.eu.example.com", "", "nobody "},
+		{"todo.example.com", "", "nobody "},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Host = tc.host
+		if tc.admin != "" {
+			req.Header.Set("X-Admin", tc.admin)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Body.String() != tc.want {
+			t.Fatalf("%s: body = %q, want %q", tc.host, rec.Body.String(), tc.want)
+		}
+	}
+}
+
+func TestRouter_MatcherConflicts(t *testing.T) {
+	r := versionedRouter()
+	err := r.Handle(http.MethodGet, "/api/todos/:uid", say("again"), When(MediaType("application/vnd.todo.v2+json")))
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Handle() err = %v, want %v", err, ErrConflict)
+	}
+	err = r.Handle(http.MethodGet, "/api/todos/:id", say("v3"), When(MediaType("application/vnd.todo.v3+json")))
+	if err != nil {
+		t.Fatalf("Handle() v3 err = %v", err)
+	}
+}
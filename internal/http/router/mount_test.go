@@ -0,0 +1,103 @@
+package router
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"todo-api/internal/pkg"
+)
+
+// echoPath writes the path it sees and the scope parameters.
+func echoPath(w http.ResponseWriter, r *http.Request) {
+	s := pkg.ScopeFrom(r)
+	io.WriteString(w, r.Method+" "+r.URL.Path+" tenant="+s.Params["tenant"]+" id="+s.Params["id"])
+}
+
+func serve(h http.Handler, method, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestRouter_Mount_SubRouter(t *testing.T) {
+	sub := &Router{}
+	sub.Handle(http.MethodGet, "/todos/:id", http.HandlerFunc(echoPath))
+
+	var seen string
+	r := &Router{}
+	r.Group("/tenants/:tenant", func(g *Router) {
+		g.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = r.URL.Path
+				next.ServeHTTP(w, r)
+			})
+		})
+		g.Mount("/api", sub)
+	})
+	r.Handle(http.MethodGet, "/tenants/:tenant/api/version", http.HandlerFunc(ok))
+
+	rec := serve(r, http.MethodGet, "/tenants/acme/api/todos/7")
+	if got, want := rec.Body.String(), "GET /todos/7 tenant=acme id=7"; got != want {
+		t.Fatalf("body = %q, want %q", got, want)
+	}
+	if seen != "/tenants/acme/api/todos/7" {
+		t.Fatalf("middleware saw %q, want the full path", seen)
+	}
+
+	// the sub-router answers what it doesn't know
+	if rec := serve(r, http.MethodPost, "/tenants/acme/api/todos/7"); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST status = %d, want 405", rec.Code)
+	}
+	if rec := serve(r, http.MethodGet, "/tenants/acme/api/nope"); rec.Code != http.StatusNotFound {
+		t.Fatalf("GET status = %d, want 404", rec.Code)
+	}
+	// routes below the prefix win
+	if rec := serve(r, http.MethodGet, "/tenants/acme/api/version"); rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Fatalf("GET version = %d %q, want the route", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRouter_Mount_Paths(t *testing.T) {
+	r := &Router{}
+	if err := r.Mount("/static", http.HandlerFunc(echoPath)); err != nil {
+		t.Fatalf("Mount() err = %v", err)
+	}
+	for target, want := range map[string]string{
+		"/static":            "DELETE / tenant= id=",
+		"/static/":           "DELETE / tenant= id=",
+		"/static/css/":       "DELETE /css/ tenant= id=",
+		"/static/a%20b/c.js": "DELETE /a b/c.js tenant= id=",
+	} {
+		if got := serve(r, http.MethodDelete, target).Body.String(); got != want {
+			t.Fatalf("%s = %q, want %q", target, got, want)
+		}
+	}
+	if rec := serve(r, http.MethodGet, "/other/"); rec.Code != http.StatusNotFound {
+		t.Fatalf("trailing slash outside mounts = %d, want 404", rec.Code)
+	}
+	if err := r.Mount("/static", http.HandlerFunc(ok)); err == nil {
+		t.Fatalf("Mount() twice expected error")
+	}
+}
+
+func TestRouter_Mount_FileServer(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := &Router{}
+	r.Mount("/static", http.FileServer(http.Dir(dir)))
+
+	if rec := serve(r, http.MethodGet, "/static/hello.txt"); rec.Code != http.StatusOK || rec.Body.String() != "hello" {
+		t.Fatalf("GET = %d %q, want 200 hello", rec.Code, rec.Body.String())
+	}
+	if rec := serve(r, http.MethodGet, "/static/"); rec.Code != http.StatusOK {
+		t.Fatalf("GET listing = %d, want 200", rec.Code)
+	}
+	if rec := serve(r, http.MethodGet, "/static/missing.txt"); rec.Code != http.StatusNotFound {
+		t.Fatalf("GET missing = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package router
+
+import (
+	"time"
+	"todo-api/internal/http/middleware"
+)
+
+// RouteOption configures a route in Handle.
+type RouteOption func(*Route)
+
+// Name names the route, so URL can build its path.
+func Name(name string) RouteOption {
+	return func(r *Route) {
+		r.Name = name
+	}
+}
+
+// With wraps the handler of the route in mws, inside the middleware of its
+// router and groups.
+func With(mws ...middleware.Middleware) RouteOption {
+	return func(r *Route) {
+		r.mws = append(r.mws, mws...)
+	}
+}
+
+// Auth adds requirements to the route, such as scopes or roles, that an
+// authorization middleware checks. The router itself doesn't.
+func Auth(requirements ...string) RouteOption {
+	return func(r *Route) {
+		r.meta.Auth = append(r.meta.Auth, requirements...)
+	}
+}
+
+// RateLimit puts the route in a rate limit class, for a rate limiting
+// middleware to pick the limit.
+func RateLimit(class string) RouteOption {
+	return func(r *Route) {
+		r.meta.RateLimit = class
+	}
+}
+
+// BodyLimit caps the size of request bodies to the route at n bytes, as
+// middleware.RouteLimits enforces.
+func BodyLimit(n int64) RouteOption {
+	return func(r *Route) {
+		r.meta.BodyLimit = n
+	}
+}
+
+// Timeout bounds the time spent on a request to the route, as
+// middleware.RouteLimits enforces.
+func Timeout(d time.Duration) RouteOption {
+	return func(r *Route) {
+		r.meta.Timeout = d
+	}
+}
+
+// Meta sets metadata of the route under key, for middleware outside this
+// package to read from the scope.
+func Meta(key string, value any) RouteOption {
+	return func(r *Route) {
+		if r.meta.Values == nil {
+			r.meta.Values = make(map[string]any)
+		}
+		r.meta.Values[key] = value
+	}
+}
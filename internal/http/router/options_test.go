@@ -0,0 +1,80 @@
+package router
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+	"todo-api/internal/http/middleware"
+	"todo-api/internal/pkg"
+)
+
+// trace returns middleware writing name before the response of the next
+// handler.
+func trace(name string) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name+" ")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestRouter_MiddlewareOrder(t *testing.T) {
+	r := &Router{}
+	r.Use(trace("root1"))
+	r.Group("/api", func(api *Router) {
+		api.Use(trace("api"))
+		api.Group("/todos", func(todos *Router) {
+			todos.Handle(http.MethodGet, "/:id", say("handler"), With(trace("route1"), trace("route2")))
+			todos.Use(trace("todos")) // after Handle
+		})
+		api.Handle(http.MethodGet, "/version", say("version"))
+	})
+	r.Use(trace("root2")) // after the groups
+
+	for _, tc := range []struct{ path, want string }{
+		{"/api/todos/1", "root1 root2 api todos route1 route2 handler "},
+		{"/api/version", "root1 root2 api version "},
+	} {
+		if got := serve(r, http.MethodGet, tc.path).Body.String(); got != tc.want {
+			t.Fatalf("GET %s = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+
+	got := r.Routes()[0].Middleware
+	want := []string{"router.trace", "router.trace", "router.trace", "router.trace", "router.trace", "router.trace"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Routes()[0].Middleware = %v, want %v", got, want)
+	}
+}
+
+func TestRouter_RouteMeta(t *testing.T) {
+	var meta *pkg.RouteMeta
+	r := &Router{}
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			meta = pkg.RouteFrom(r)
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Handle(http.MethodPost, "/todos", http.HandlerFunc(ok),
+		Name("todos"), Auth("todos:write"), RateLimit("writes"),
+		BodyLimit(1<<10), Timeout(2*time.Second), Meta("audit", true))
+
+	serve(r, http.MethodPost, "/todos")
+	if meta == nil {
+		t.Fatalf("RouteFrom() = nil")
+	}
+	if meta.Name != "todos" || meta.Method != http.MethodPost || meta.Pattern != "/todos" ||
+		!slices.Equal(meta.Auth, []string{"todos:write"}) || meta.RateLimit != "writes" ||
+		meta.BodyLimit != 1<<10 || meta.Timeout != 2*time.Second || meta.Values["audit"] != true {
+		t.Fatalf("RouteFrom() = %+v", meta)
+	}
+
+	info := r.Routes()[0]
+	if info.RateLimit != "writes" || info.BodyLimit != 1<<10 || info.Timeout != "2s" {
+		t.Fatalf("Routes()[0] = %+v", info)
+	}
+}
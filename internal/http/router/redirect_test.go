@@ -0,0 +1,83 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRouter_Redirects(t *testing.T) {
+	r := &Router{}
+	r.Handle(http.MethodGet, "/api/v1/todos/:id", http.HandlerFunc(getID))
+	r.Handle(http.MethodPost, "/api/v1/todos", http.HandlerFunc(ok))
+	r.Mount("/static", http.HandlerFunc(ok))
+
+	for _, tc := range []struct {
+		method, target string
+		code           int
+		location       string
+	}{
+		{"GET", "/api/v1/todos/1/", http.StatusMovedPermanently, "/api/v1/todos/1"},
+		{"HEAD", "/api/v1/todos/1//", http.StatusMovedPermanently, "/api/v1/todos/1"},
+		{"POST", "/api/v1/todos/", http.StatusPermanentRedirect, "/api/v1/todos"},
+		{"GET", "/api//v1/./todos/1?x=1", http.StatusMovedPermanently, "/api/v1/todos/1?x=1"},
+		{"GET", "/api/v2/../v1/todos/a%20b", http.StatusMovedPermanently, "/api/v1/todos/a%20b"},
+		{"GET", "/static//css/", http.StatusMovedPermanently, "/static/css/"},
+		// the canonical path has the route, but not for the method
+		{"DELETE", "/api/v1/todos/1/", http.StatusPermanentRedirect, "/api/v1/todos/1"},
+		{"GET", "/api/v1/nope/", http.StatusNotFound, ""},
+		{"GET", "/api/v1/", http.StatusNotFound, ""},
+		{"GET", "/static/css/", http.StatusOK, ""},
+	} {
+		rec := serve(r, tc.method, tc.target)
+		if rec.Code != tc.code || rec.Header().Get("Location") != tc.location {
+			t.Fatalf("%s %s = %d %q, want %d %q", tc.method, tc.target, rec.Code, rec.Header().Get("Location"), tc.code, tc.location)
+		}
+	}
+}
+
+func TestRouter_RedirectInMountedRouter(t *testing.T) {
+	sub := &Router{}
+	sub.Handle(http.MethodGet, "/todos/:id", http.HandlerFunc(getID))
+	r := &Router{}
+	r.Mount("/api/v1", sub)
+
+	rec := serve(r, http.MethodGet, "/api/v1/todos//1")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/api/v1/todos/1" {
+		t.Fatalf("got %d %q, want 301 /api/v1/todos/1", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestRouter_WithRedirects(t *testing.T) {
+	r := New(WithRedirects(RedirectCleanPath))
+	r.Handle(http.MethodGet, "/todos/:id", http.HandlerFunc(getID))
+
+	if rec := serve(r, http.MethodGet, "/todos/1/"); rec.Code != http.StatusNotFound {
+		t.Fatalf("trailing slash = %d, want 404", rec.Code)
+	}
+	if rec := serve(r, http.MethodGet, "/todos/./1"); rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("dot segment = %d, want 301", rec.Code)
+	}
+
+	r = New(WithRedirects(RedirectNone))
+	r.Handle(http.MethodGet, "/todos/:id", http.HandlerFunc(getID))
+	if rec := serve(r, http.MethodGet, "/todos//1"); rec.Code != http.StatusNotFound {
+		t.Fatalf("duplicate slash = %d, want 404", rec.Code)
+	}
+}
+
+func TestRouter_WithCaseInsensitive(t *testing.T) {
+	r := New(WithCaseInsensitive())
+	r.Handle(http.MethodGet, "/API/todos/:id", http.HandlerFunc(getID))
+
+	rec := serve(r, http.MethodGet, "/api/TODOS/AbC")
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"id\":\"AbC\"}\n" {
+		t.Fatalf("got %d %q, want the route with id AbC", rec.Code, rec.Body.String())
+	}
+	if err := r.Handle(http.MethodGet, "/api/Todos/:id", http.HandlerFunc(ok)); err == nil {
+		t.Fatalf("Handle() expected conflict with a differently cased pattern")
+	}
+
+	if rec := serve(&Router{}, http.MethodGet, "/API/todos/1"); rec.Code != http.StatusNotFound {
+		t.Fatalf("case sensitive by default, got %d", rec.Code)
+	}
+}
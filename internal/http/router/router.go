@@ -1,7 +1,12 @@
 package router
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	stdpath "path"
+	"slices"
 	"strings"
 	httpx "todo-api/internal/http"
 	"todo-api/internal/http/middleware"
@@ -13,31 +18,102 @@ type Route struct {
 	Method  string
 	Pattern string
 	Handler http.Handler
+	// Name identifies the route for URL, routes of one pattern can share it.
+	Name string
+
+	segs    []path.Segment
+	params  []string     // names of the pattern's parameters and wildcard in order
+	handler http.Handler // as registered
+	inner   http.Handler // handler, for mounts with the prefix stripped
+	group   *Router
+	mws     []middleware.Middleware // of the route itself, see With
+	mount   bool
+	meta    pkg.RouteMeta
+
+	matchers []Matcher
 }
 
-type routeKey struct {
-	Method  string // UPPER
-	Pattern string // UPPER NORM
+// anyMethod is the method of mounts, which serve every method.
+const anyMethod = "*"
+
+// table holds the routes of a router and all its groups.
+type table struct {
+	root   node
+	names  map[string]*Route
+	routes []*Route // in registration order
+	err    error    // of the first failed Handle
+
+	noRedirect RedirectPolicy // the redirects turned off
 }
 
-func newRouteKey(r Route) routeKey {
+// RedirectPolicy selects the kinds of non-canonical paths that are
+// redirected to their canonical form, if a route matches that.
+type RedirectPolicy int
+
+const (
+	// RedirectTrailingSlash sends /todos/ to /todos.
+	RedirectTrailingSlash RedirectPolicy = 1 << iota
+	// RedirectCleanPath sends /api//todos/./1 and /api/x/../todos/1 to
+	// /api/todos/1.
+	RedirectCleanPath
+
+	RedirectNone RedirectPolicy = 0
+	RedirectAll                 = RedirectTrailingSlash | RedirectCleanPath
+)
+
+// Option configures a Router made by New.
+type Option func(*table)
 
-	normPat := "/" + strings.Trim(strings.TrimSpace(r.Pattern), "/")
-	return routeKey{
-		Method:  strings.ToUpper(strings.TrimSpace(r.Method)),
-		Pattern: normPat,
+// WithRedirects sets the redirects of the router, RedirectAll by default.
+// Paths that are not redirected are not found.
+func WithRedirects(p RedirectPolicy) Option {
+	return func(t *table) {
+		t.noRedirect = RedirectAll &^ p
 	}
 }
 
-func areRoutesEqual(r1, r2 Route) bool {
-	rk1, rk2 := newRouteKey(r1), newRouteKey(r2)
-	return rk1 == rk2
+// WithCaseInsensitive makes the literal segments of patterns match paths in
+// any case, /TODOS/1 matches /todos/:id.
+func WithCaseInsensitive() Option {
+	return func(t *table) {
+		t.root.fold = true
+	}
+}
+
+// New returns a router configured by opts. A zero Router is one made by New
+// without options.
+func New(opts ...Option) *Router {
+	t := &table{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return &Router{t: t}
 }
 
+var (
+	ErrDuplicateName = errors.New("duplicate route name")
+	ErrUnknownRoute  = errors.New("unknown route")
+	ErrURLParams     = errors.New("bad URL parameters")
+	ErrConflict      = errors.New("conflicting route")
+)
+
+// Router dispatches requests to the routes registered on it and its groups.
+//
+// Middleware wraps the handler of a route in this order, outermost first:
+// the middleware of the router, then that of each group down to the route's
+// own, each in the order of Use, then the middleware of the route's With
+// options. Use applies to the routes of the router and all its groups,
+// whether they were registered before or after. All middleware runs after
+// routing, with the parameters and the route metadata in the scope; wrap
+// the router for middleware that must see every request.
+//
+// Routes and middleware must be set up before the router serves requests.
 type Router struct {
-	routes []Route
-	base   string
-	mws    []middleware.Middleware
+	t        *table
+	base     string
+	parent   *Router
+	mws      []middleware.Middleware
+	matchers []Matcher
 }
 
 func joinPath(a, b string) string {
@@ -45,71 +121,373 @@ func joinPath(a, b string) string {
 		"/" + strings.TrimPrefix(b, "/")
 }
 
+// table returns the route table, creating it for a zero Router.
+func (router *Router) table() *table {
+	if router.t == nil {
+		router.t = &table{}
+	}
+	return router.t
+}
+
 func (router *Router) Group(path string, fn func(*Router)) {
 	child := &Router{
-		routes: router.routes,
+		t:      router.table(),
 		base:   joinPath(router.base, path),
-		mws:    append([]middleware.Middleware{}, router.mws...),
+		parent: router,
+
+		matchers: slices.Clone(router.matchers),
 	}
 	fn(child)
+}
 
-	router.routes = child.routes
+// Match applies the matchers to the routes registered on the router and its
+// groups from now on. Unlike Use, it leaves the routes registered before, as
+// their matchers decide which of them conflict.
+func (router *Router) Match(ms ...Matcher) {
+	router.matchers = append(router.matchers, ms...)
 }
 
+// Use adds middleware to the routes of the router and its groups, see Router
+// for the order.
 func (router *Router) Use(mws ...middleware.Middleware) {
 	router.mws = append(router.mws, mws...)
+	for _, route := range router.table().routes {
+		if route.group.within(router) {
+			route.build()
+		}
+	}
+}
+
+// within reports whether router is group or one of its groups.
+func (router *Router) within(group *Router) bool {
+	for ; router != nil; router = router.parent {
+		if router == group {
+			return true
+		}
+	}
+	return false
+}
+
+// middleware returns the middleware of the router and its parents, outermost
+// first.
+func (router *Router) middleware() []middleware.Middleware {
+	if router.parent == nil {
+		return slices.Clone(router.mws)
+	}
+	return append(router.parent.middleware(), router.mws...)
+}
+
+// middleware returns all middleware wrapping the route, outermost first.
+func (route *Route) middleware() []middleware.Middleware {
+	return append(route.group.middleware(), route.mws...)
+}
+
+// build wraps the route's handler in its middleware.
+func (route *Route) build() {
+	route.Handler = middleware.Chain(route.middleware()...)(route.inner)
+}
+
+// Handle registers h for method and pattern below the router's base, see
+// path.Segment for the pattern syntax. A bad pattern is reported here rather
+// than on every request, as is a name already used for another pattern and
+// a route matching the same paths as one of the same method, such as
+// /todos/:id and /todos/:uid. Err returns the first of these errors.
+func (router *Router) Handle(method, pattern string, h http.Handler, opts ...RouteOption) error {
+	return router.keepErr(router.handle(method, pattern, h, opts, false))
+}
+
+// Mount serves h for prefix and every path below it, whatever the method,
+// behind the middleware of the router. h sees the path below the prefix, "/"
+// for the prefix itself, and the parameters of the prefix in the scope, so
+// file servers and other routers can be mounted. Unlike other routes, paths
+// ending in a slash reach h. Routes registered below prefix take precedence.
+func (router *Router) Mount(prefix string, h http.Handler, opts ...RouteOption) error {
+	return router.keepErr(router.handle(anyMethod, prefix, h, opts, true))
 }
 
-func (router *Router) Handle(method, pattern string, h http.Handler) {
+func (router *Router) keepErr(err error) error {
+	if t := router.table(); err != nil && t.err == nil {
+		t.err = err
+	}
+	return err
+}
+
+// Err returns the first error of Handle on the router or its groups, so that
+// a list of routes can be checked once.
+func (router *Router) Err() error {
+	return router.table().err
+}
+
+func (router *Router) handle(method, pattern string, h http.Handler, opts []RouteOption, mount bool) error {
 	pattern = joinPath(router.base, pattern)
-	h = middleware.Chain(router.mws...)(h)
-	sb := strings.Builder{}
-	sb.WriteByte('/')
-	sb.WriteString(strings.Trim(strings.TrimSpace(pattern), "/"))
+	pattern = "/" + strings.Trim(strings.TrimSpace(pattern), "/")
+	segs, err := path.Parse(pattern)
+	if err != nil {
+		return err
+	}
+	var params []string
+	for _, seg := range segs {
+		if seg.Param || seg.Wildcard {
+			params = append(params, seg.Value)
+		}
+	}
 
-	route := Route{
-		Method:  method,
-		Pattern: sb.String(),
-		Handler: h,
+	route := &Route{
+		Method:  strings.ToUpper(strings.TrimSpace(method)),
+		Pattern: pattern,
+		segs:    segs,
+		params:  params,
+		handler: h,
+		inner:   h,
+		group:   router,
+		mount:   mount,
+
+		matchers: slices.Clone(router.matchers),
+	}
+	if mount {
+		if n := len(segs); n > 0 && segs[n-1].Wildcard {
+			return fmt.Errorf("%w: mount prefix %s ends in a wildcard", path.ErrInvalidPattern, pattern)
+		}
+		route.inner = stripSegments{n: len(segs), h: h}
+		// the unnamed tail is left out of params
+		route.segs = append(segs, path.Segment{Wildcard: true, Optional: true})
+	}
+	for _, opt := range opts {
+		opt(route)
 	}
+	route.meta.Name, route.meta.Method, route.meta.Pattern = route.Name, route.Method, route.Pattern
+	route.build()
 
-	// find routes with same path and rewrite
-	for i, r := range router.routes {
-		if areRoutesEqual(r, route) {
-			router.routes[i] = route
-			return
+	t := router.table()
+	if other, ok := t.names[route.Name]; ok && route.Name != "" && other.Pattern != pattern {
+		return fmt.Errorf("%w: %q names %s already", ErrDuplicateName, route.Name, other.Pattern)
+	}
+	ends := t.root.insert(route.segs)
+	for _, n := range ends {
+		if other := n.conflict(route); other != nil {
+			return fmt.Errorf("%w: %s %s matches the requests of %s", ErrConflict, route.Method, pattern, other.Pattern)
 		}
 	}
 
-	// add path if new
-	router.routes = append(router.routes, route)
+	for _, n := range ends {
+		n.set(route)
+	}
+	if route.Name != "" {
+		if t.names == nil {
+			t.names = make(map[string]*Route)
+		}
+		t.names[route.Name] = route
+	}
+	t.routes = append(t.routes, route)
+	return nil
 }
 
+// ServeHTTP dispatches r to its route. HEAD without a route of its own is
+// served by the GET route, OPTIONS without one gets 204 and the Allow header.
+// Paths with a trailing slash, empty, . or .. segments are redirected to
+// their canonical form as the RedirectPolicy allows.
 func (router *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	var allow []string
-	for _, route := range router.routes {
-		ok, vals, err := path.Match(route.Pattern, r.URL.EscapedPath())
-		if err != nil {
-			httpx.WriteError(w, http.StatusBadRequest, "invalid_path", "unable to handle path")
+	t := router.table()
+	p := r.URL.EscapedPath()
+	if len(p) == 0 || p[0] != '/' {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid_path", "unable to handle path")
+		return
+	}
+	trimmed := strings.TrimRight(p, "/")
+	if trimmed == "" {
+		trimmed = "/"
+	}
+	canonical := stdpath.Clean(p)
+
+	m, head, err := t.find(r, trimmed)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid_path", "unable to handle path")
+		return
+	}
+	switch {
+	case canonical != trimmed:
+		t.redirect(w, r, RedirectCleanPath, p, canonical)
+		return
+	case trimmed != p && (m.route == nil || !m.route.mount):
+		// only mounts take paths with a trailing slash, as they are
+		t.redirect(w, r, RedirectTrailingSlash, p, canonical)
+		return
+	}
+	if head {
+		w = headWriter{w}
+	}
+	for _, h := range m.vary {
+		w.Header().Add("Vary", h)
+	}
+	if m.route != nil {
+		r = pkg.WithScope(r, m.route.scope(r, m.vals, m.params))
+		m.route.Handler.ServeHTTP(w, r)
+		return
+	}
+
+	if allow := allowHeader(t.root.allowed(trimmed, r)); allow != "" {
+		w.Header().Set("Allow", allow)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		if ok {
-			if r.Method != route.Method {
-				allow = append(allow, route.Method)
-				continue
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "requested method not allowed")
+		return
+	}
+
+	httpx.WriteError(w, http.StatusNotFound, "path_not_found", "requested path not found")
+}
+
+// find looks up the route for r at the escaped path p, a GET route for HEAD
+// if there is no HEAD route.
+func (t *table) find(r *http.Request, p string) (m match, head bool, err error) {
+	m, err = t.root.lookup(r.Method, p, r)
+	if m.route == nil && err == nil && r.Method == http.MethodHead {
+		m, err = t.root.lookup(http.MethodGet, p, r)
+		head = m.route != nil
+	}
+	return m, head, err
+}
+
+// redirect sends r from the escaped path p to the canonical one, if kind is
+// redirected and a route matches there. Otherwise the path is not found.
+// GET and HEAD get 301, other methods 308 so they are repeated as they are.
+func (t *table) redirect(w http.ResponseWriter, r *http.Request, kind RedirectPolicy, p, canonical string) {
+	m, _, err := t.find(r, canonical)
+	if t.noRedirect&kind != 0 || err != nil ||
+		m.route == nil && len(t.root.allowed(canonical, r)) == 0 {
+		httpx.WriteError(w, http.StatusNotFound, "path_not_found", "requested path not found")
+		return
+	}
+	if m.route != nil && m.route.mount && strings.HasSuffix(p, "/") && canonical != "/" {
+		canonical += "/"
+	}
+
+	// in a mounted router p is the end of the path the client used
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		if full := u.EscapedPath(); strings.HasSuffix(full, p) {
+			canonical = full[:len(full)-len(p)] + canonical
+		}
+	}
+	if r.URL.RawQuery != "" {
+		canonical += "?" + r.URL.RawQuery
+	}
+	code := http.StatusPermanentRedirect
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	http.Redirect(w, r, canonical, code)
+}
+
+// allowHeader lists the registered methods and those the router answers
+// for them: HEAD where there is GET, and OPTIONS.
+func allowHeader(methods []string) string {
+	if len(methods) == 0 {
+		return ""
+	}
+	all := slices.Clone(methods)
+	if slices.Contains(all, http.MethodGet) && !slices.Contains(all, http.MethodHead) {
+		all = append(all, http.MethodHead)
+	}
+	if !slices.Contains(all, http.MethodOptions) {
+		all = append(all, http.MethodOptions)
+	}
+	return strings.Join(all, ", ")
+}
+
+// headWriter answers HEAD with a GET handler, keeping the header and
+// dropping the body.
+type headWriter struct {
+	http.ResponseWriter
+}
+
+func (w headWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// scope returns the scope for route, the values of its parameters and the
+// parameters its matchers captured, with the route's metadata. The scope of
+// the router r is mounted in is kept, with the parameters added.
+func (route *Route) scope(r *http.Request, vals []string, captured map[string]string) *pkg.Scope {
+	s := &pkg.Scope{}
+	var inherited map[string]string
+	if parent := pkg.ScopeFrom(r); parent != nil {
+		*s = *parent
+		inherited = parent.Params
+	}
+	s.Route = &route.meta
+	s.Params = route.paramMap(vals, len(captured)+len(inherited))
+	for _, more := range []map[string]string{captured, inherited} {
+		for k, v := range more {
+			if _, ok := s.Params[k]; !ok {
+				s.Params[k] = v
 			}
+		}
+	}
+	return s
+}
 
-			scope := &pkg.Scope{Params: vals}
-			r = pkg.WithScope(r, scope)
-			route.Handler.ServeHTTP(w, r)
-			return
+// match reports whether r satisfies the matchers of the route and returns
+// the parameters they captured.
+func (route *Route) match(r *http.Request) (map[string]string, bool) {
+	var params map[string]string
+	for _, m := range route.matchers {
+		p, ok := m.Match(r)
+		if !ok {
+			return nil, false
+		}
+		if params == nil {
+			params = p
+			continue
+		}
+		for k, v := range p {
+			params[k] = v
 		}
 	}
-	if len(allow) > 0 {
-		w.Header().Set("Allow", strings.Join(allow, ", "))
-		httpx.WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "requested method not allowed")
+	return params, true
+}
+
+// paramMap pairs the parameter names of the route with vals, which lacks
+// the optional parameters missing from the path and has the tail of mounts.
+func (route *Route) paramMap(vals []string, extra int) map[string]string {
+	vals = vals[:min(len(vals), len(route.params))]
+	params := make(map[string]string, len(vals)+extra)
+	for i, v := range vals {
+		params[route.params[i]] = v
+	}
+	return params
+}
+
+// stripSegments serves h with the first n segments removed from the path.
+type stripSegments struct {
+	n int
+	h http.Handler
+}
+
+func (s stripSegments) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest := r.URL.EscapedPath()
+	for range s.n {
+		i := strings.IndexByte(rest[1:], '/')
+		if i < 0 {
+			rest = "/"
+			break
+		}
+		rest = rest[i+1:]
+	}
+	p, err := url.PathUnescape(rest)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid_path", "unable to handle path")
 		return
 	}
 
-	httpx.WriteError(w, http.StatusNotFound, "path_not_found", "requested path not found")
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = p
+	r2.URL.RawPath = ""
+	if rest != p {
+		r2.URL.RawPath = rest
+	}
+	s.h.ServeHTTP(w, r2)
 }
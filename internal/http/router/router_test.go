@@ -2,9 +2,11 @@ package router
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"todo-api/internal/http/path"
 	"todo-api/internal/pkg"
 )
 
@@ -71,8 +73,8 @@ func TestRouter_405_AllowHeader(t *testing.T) {
 
 	allow := rec.Header().Get("Allow")
 
-	if !(allow == "GET, DELETE" || allow == "DELETE, GET") {
-		t.Fatalf("want Allow to contain GET, DELETE: got %q", allow)
+	if allow != "GET, DELETE, HEAD, OPTIONS" {
+		t.Fatalf("want Allow GET, DELETE, HEAD, OPTIONS: got %q", allow)
 	}
 }
 
@@ -103,3 +105,170 @@ func TestRouter_400_BadEncoding(t *testing.T) {
 		t.Fatalf("want 400, got %d", rec.Code)
 	}
 }
+
+func TestRouter_StaticBeatsParam(t *testing.T) {
+	r := &Router{}
+	r.Handle(http.MethodGet, "/todos/:id", http.HandlerFunc(getID))
+	r.Handle(http.MethodGet, "/todos/new", http.HandlerFunc(ok))
+	r.Handle(http.MethodGet, "/todos/:id/edit", http.HandlerFunc(getID))
+
+	tests := []struct {
+		path   string
+		wantID string
+	}{
+		{"/todos/new", ""},
+		{"/todos/42", "42"},
+		// falls back to the parameter when the literal leads nowhere
+		{"/todos/new/edit", "new"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: want 200, got %d", tt.path, rec.Code)
+		}
+		var body map[string]string
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		if body["id"] != tt.wantID {
+			t.Fatalf("%s: want id %q, got %q", tt.path, tt.wantID, body["id"])
+		}
+	}
+}
+
+func TestRouter_MethodFallsBackToParam(t *testing.T) {
+	r := &Router{}
+	r.Handle(http.MethodGet, "/todos/new", http.HandlerFunc(ok))
+	r.Handle(http.MethodDelete, "/todos/:id", http.HandlerFunc(getID))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/todos/new", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/todos/new", nil))
+	if allow := rec.Header().Get("Allow"); rec.Code != http.StatusMethodNotAllowed || allow != "GET, DELETE, HEAD, OPTIONS" {
+		t.Fatalf("want 405 with GET, DELETE, HEAD, OPTIONS, got %d %q", rec.Code, allow)
+	}
+}
+
+func TestRouter_Handle_InvalidPattern(t *testing.T) {
+	r := &Router{}
+	if err := r.Handle(http.MethodGet, "/x/:id/:id", http.HandlerFunc(ok)); err == nil {
+		t.Fatalf("want error for duplicate param")
+	}
+	if err := r.Handle(http.MethodGet, "/x//y", http.HandlerFunc(ok)); err == nil {
+		t.Fatalf("want error for empty segment")
+	}
+}
+
+func TestLookup_StaticDoesNotAllocate(t *testing.T) {
+	r := benchRouter(&Router{})
+	root := &r.(*Router).t.root
+
+	allocs := testing.AllocsPerRun(100, func() {
+		m, _ := root.lookup(http.MethodGet, "/api/v1/res49/items", nil)
+		if m.route == nil {
+			t.Fatalf("no route")
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("want 0 allocs, got %v", allocs)
+	}
+}
+
+// named answers with the name it was created with.
+func named(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := map[string]string{"route": name}
+		for k, v := range pkg.ScopeFrom(r).Params {
+			params[k] = v
+		}
+		_ = json.NewEncoder(w).Encode(params)
+	})
+}
+
+func TestRouter_MostSpecificMatch(t *testing.T) {
+	r := &Router{}
+	for _, p := range []string{"/files/*path", "/files/:name", "/files/:id<int>", "/files/readme", "/todos/:id?"} {
+		if err := r.Handle(http.MethodGet, p, named(p)); err != nil {
+			t.Fatalf("Handle(%q) err = %v", p, err)
+		}
+	}
+
+	tests := []struct {
+		path, route, param, value string
+	}{
+		{"/files/readme", "/files/readme", "", ""},
+		{"/files/42", "/files/:id<int>", "id", "42"},
+		{"/files/notes", "/files/:name", "name", "notes"},
+		{"/files/a/b", "/files/*path", "path", "a/b"},
+		{"/todos", "/todos/:id?", "id", ""},
+		{"/todos/5", "/todos/:id?", "id", "5"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		var body map[string]string
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		if rec.Code != http.StatusOK || body["route"] != tt.route || body[tt.param] != tt.value {
+			t.Fatalf("%s: got %d %v, want %s with %s=%q", tt.path, rec.Code, body, tt.route, tt.param, tt.value)
+		}
+	}
+}
+
+func TestRouter_Handle_InvalidConstraint(t *testing.T) {
+	r := &Router{}
+	err := r.Handle(http.MethodGet, "/x/:id<nope>", http.HandlerFunc(ok))
+	if !errors.Is(err, path.ErrInvalidPattern) {
+		t.Fatalf("want ErrInvalidPattern, got %v", err)
+	}
+}
+
+func TestRouter_HeadFallsBackToGet(t *testing.T) {
+	r := &Router{}
+	r.Handle(http.MethodGet, "/todos/:id", http.HandlerFunc(getID))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/todos/7", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("want 200 with the GET header, got %d %v", rec.Code, rec.Header())
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("want empty body, got %q", rec.Body.String())
+	}
+
+	// a HEAD route of its own wins
+	r.Handle(http.MethodHead, "/todos/:id", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/todos/7", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("want 204, got %d", rec.Code)
+	}
+}
+
+func TestRouter_Options(t *testing.T) {
+	r := &Router{}
+	r.Handle(http.MethodPost, "/todos", http.HandlerFunc(ok))
+	r.Handle(http.MethodGet, "/todos/:id", http.HandlerFunc(getID))
+	r.Handle(http.MethodDelete, "/todos/:id", http.HandlerFunc(ok))
+
+	for _, tc := range []struct {
+		path  string
+		code  int
+		allow string
+	}{
+		{"/todos", http.StatusNoContent, "POST, OPTIONS"},
+		{"/todos/7", http.StatusNoContent, "GET, DELETE, HEAD, OPTIONS"},
+		{"/nope", http.StatusNotFound, ""},
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, tc.path, nil))
+		if rec.Code != tc.code || rec.Header().Get("Allow") != tc.allow {
+			t.Fatalf("OPTIONS %s = %d %q, want %d %q", tc.path, rec.Code, rec.Header().Get("Allow"), tc.code, tc.allow)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+	Name    string `json:"name,omitempty"`
+	// Match describes the matchers of the route.
+	Match []string `json:"match,omitempty"`
+	// Middleware lists the functions wrapping the handler, outermost first.
+	Middleware []string `json:"middleware"`
+	Handler    string   `json:"handler"`
+	Auth       []string `json:"auth,omitempty"`
+	RateLimit  string   `json:"rate_limit,omitempty"`
+	BodyLimit  int64    `json:"body_limit,omitempty"`
+	Timeout    string   `json:"timeout,omitempty"`
+}
+
+// Routes lists the routes of the router and all its groups in the order
+// they were registered.
+func (router *Router) Routes() []RouteInfo {
+	routes := router.table().routes
+	infos := make([]RouteInfo, len(routes))
+	for i, route := range routes {
+		chain := route.middleware()
+		mws := make([]string, len(chain))
+		for j, mw := range chain {
+			mws[j] = funcName(mw)
+		}
+		var match []string
+		for _, m := range route.matchers {
+			match = append(match, m.String())
+		}
+		infos[i] = RouteInfo{
+			Match:      match,
+			Method:     route.Method,
+			Pattern:    route.Pattern,
+			Name:       route.Name,
+			Middleware: mws,
+			Handler:    handlerName(route.handler),
+			Auth:       route.meta.Auth,
+			RateLimit:  route.meta.RateLimit,
+			BodyLimit:  route.meta.BodyLimit,
+		}
+		if route.meta.Timeout > 0 {
+			infos[i].Timeout = route.meta.Timeout.String()
+		}
+	}
+	return infos
+}
+
+func handlerName(h http.Handler) string {
+	if fn, ok := h.(http.HandlerFunc); ok {
+		return funcName(fn)
+	}
+	return fmt.Sprintf("%T", h)
+}
+
+// funcName returns the name of the function fn as package.Func, with the
+// closures of constructors such as middleware.Tenant named after them.
+func funcName(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "?"
+	}
+	name := f.Name()
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm") // method values
+	// closures are Outer.func1, Outer.func1.2 or, when inlined, Outer.1
+	for {
+		i := strings.LastIndexByte(name, '.')
+		last := strings.TrimPrefix(name[i+1:], "func")
+		if i < 0 || last == "" || strings.Trim(last, "0123456789") != "" {
+			return name
+		}
+		name = name[:i]
+	}
+}
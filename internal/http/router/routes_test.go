@@ -0,0 +1,68 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"todo-api/internal/http/middleware"
+)
+
+type staticHandler struct{}
+
+func (staticHandler) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+func TestRouter_Routes(t *testing.T) {
+	r := &Router{}
+	r.Use(middleware.Logging)
+	r.Handle(http.MethodGet, "/healthz", staticHandler{})
+	r.Group("/api/v1", func(api *Router) {
+		api.Use(middleware.ReadYourWrites(0))
+		api.Handle(http.MethodGet, "/todos/:id", http.HandlerFunc(getID), Name("todo"))
+	})
+
+	want := []RouteInfo{
+		{Method: "GET", Pattern: "/healthz", Middleware: []string{"middleware.Logging"}, Handler: "router.staticHandler"},
+		{Method: "GET", Pattern: "/api/v1/todos/:id", Name: "todo",
+			Middleware: []string{"middleware.Logging", "middleware.ReadYourWrites"}, Handler: "router.getID"},
+	}
+	got := r.Routes()
+	if len(got) != len(want) {
+		t.Fatalf("Routes() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Method != w.Method || g.Pattern != w.Pattern || g.Name != w.Name ||
+			g.Handler != w.Handler || !slices.Equal(g.Middleware, w.Middleware) {
+			t.Fatalf("Routes()[%d] = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestRouter_Handle_Conflicts(t *testing.T) {
+	r := &Router{}
+	r.Handle(http.MethodGet, "/todos/:id", http.HandlerFunc(getID))
+	r.Handle(http.MethodGet, "/items", http.HandlerFunc(ok))
+
+	for _, p := range []string{"/todos/:id", "/todos/:uid", "/items/:page?"} {
+		if err := r.Handle(http.MethodGet, p, http.HandlerFunc(ok)); !errors.Is(err, ErrConflict) {
+			t.Fatalf("Handle(%q) err = %v, want %v", p, err, ErrConflict)
+		}
+	}
+	// other methods, more specific parameters and wildcards can be added
+	for _, p := range []string{"/todos/:id<int>", "/todos/*rest", "/todos/new"} {
+		if err := r.Handle(http.MethodGet, p, http.HandlerFunc(ok)); err != nil {
+			t.Fatalf("Handle(%q) err = %v", p, err)
+		}
+	}
+	if err := r.Handle(http.MethodDelete, "/todos/:id", http.HandlerFunc(ok)); err != nil {
+		t.Fatalf("Handle(DELETE) err = %v", err)
+	}
+
+	if err := r.Err(); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Err() = %v, want %v", err, ErrConflict)
+	}
+	if n := len(r.Routes()); n != 6 {
+		t.Fatalf("len(Routes()) = %d, want 6", n)
+	}
+}
@@ -0,0 +1,240 @@
+package router
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"todo-api/internal/http/path"
+)
+
+// node is a segment of the route tree. Children are keyed by the decoded
+// literal segment; parameters and wildcards are kept per constraint, the
+// constrained ones first.
+type node struct {
+	static    map[string]*node
+	params    []*node
+	wildcards []*node
+	// constraint and check of a parameter or wildcard node
+	constraint string
+	check      func(string) bool
+	// fold makes literals match regardless of case, they are kept lowercase.
+	fold bool
+	// routes ending here by method, those with matchers first; methods keeps
+	// the registration order.
+	routes  map[string][]*Route
+	methods []string
+}
+
+// insert adds the nodes for segs below n and returns those a route ends at,
+// more than one when the pattern has optional segments.
+func (n *node) insert(segs []path.Segment) []*node {
+	var ends []*node
+	for _, seg := range segs {
+		if seg.Optional && ends == nil {
+			ends = append(ends, n)
+		}
+		switch {
+		case seg.Wildcard:
+			n = child(&n.wildcards, seg, n.fold)
+		case seg.Param:
+			n = child(&n.params, seg, n.fold)
+		default:
+			if n.static == nil {
+				n.static = make(map[string]*node)
+			}
+			key := seg.Value
+			if n.fold {
+				key = strings.ToLower(key)
+			}
+			c, ok := n.static[key]
+			if !ok {
+				c = &node{fold: n.fold}
+				n.static[key] = c
+			}
+			n = c
+		}
+		if seg.Optional {
+			ends = append(ends, n)
+		}
+	}
+	if ends == nil {
+		ends = append(ends, n)
+	}
+	return ends
+}
+
+// child returns the node in list for the constraint of seg, adding it if
+// needed. Unconstrained nodes go last, so they are tried last.
+func child(list *[]*node, seg path.Segment, fold bool) *node {
+	for _, c := range *list {
+		if c.constraint == seg.Constraint {
+			return c
+		}
+	}
+	c := &node{constraint: seg.Constraint, check: seg.Check, fold: fold}
+	i := len(*list)
+	if c.check != nil {
+		i = slices.IndexFunc(*list, func(c *node) bool { return c.check == nil })
+		if i < 0 {
+			i = len(*list)
+		}
+	}
+	*list = slices.Insert(*list, i, c)
+	return c
+}
+
+// conflict returns the route at n that matches the same requests as route.
+func (n *node) conflict(route *Route) *Route {
+	key := matchKey(route.matchers)
+	for _, other := range n.routes[route.Method] {
+		if matchKey(other.matchers) == key {
+			return other
+		}
+	}
+	return nil
+}
+
+// set registers route for its method at n.
+func (n *node) set(route *Route) {
+	if n.routes == nil {
+		n.routes = make(map[string][]*Route)
+	}
+	list := n.routes[route.Method]
+	if len(list) == 0 {
+		n.methods = append(n.methods, route.Method)
+	}
+	i := len(list)
+	if len(route.matchers) > 0 {
+		i = slices.IndexFunc(list, func(r *Route) bool { return len(r.matchers) == 0 })
+		if i < 0 {
+			i = len(list)
+		}
+	}
+	n.routes[route.Method] = slices.Insert(list, i, route)
+}
+
+// match is the outcome of a lookup.
+type match struct {
+	route  *Route
+	vals   []string          // of the path parameters
+	params map[string]string // captured by matchers
+	vary   []string          // headers the matchers looked at
+}
+
+// pick sets m to the route of n for method and r, a mount if there is none.
+func (n *node) pick(method string, r *http.Request, m *match) bool {
+	for _, route := range n.routes[method] {
+		for _, mt := range route.matchers {
+			if v, ok := mt.(varier); ok && !slices.Contains(m.vary, v.vary()) {
+				m.vary = append(m.vary, v.vary())
+			}
+		}
+		params, ok := route.match(r)
+		if ok {
+			m.route, m.params = route, params
+			return true
+		}
+	}
+	if method != anyMethod {
+		return n.pick(anyMethod, r, m)
+	}
+	return false
+}
+
+// visit calls fn for every node below n whose routes match the escaped path
+// p, which has no leading slash, until fn returns true. Literals are tried
+// first, then constrained and plain parameters, then wildcards; vals holds
+// the parameter values captured on the way.
+func (n *node) visit(p string, vals []string, fn func(n *node, vals []string) bool) (bool, error) {
+	seg, rest, more := strings.Cut(p, "/")
+	dec, err := url.PathUnescape(seg)
+	if err != nil {
+		return false, path.ErrBadEncoding
+	}
+
+	key := dec
+	if n.fold {
+		key = strings.ToLower(dec)
+	}
+	if c := n.static[key]; c != nil {
+		if done, err := c.next(rest, more, vals, fn); done || err != nil {
+			return done, err
+		}
+	}
+	if len(n.params) > 0 && dec != "" {
+		if strings.Contains(dec, "/") {
+			return false, path.ErrEncodedSlash
+		}
+		for _, c := range n.params {
+			if c.check != nil && !c.check(dec) {
+				continue
+			}
+			if done, err := c.next(rest, more, append(vals, dec), fn); done || err != nil {
+				return done, err
+			}
+		}
+	}
+	if len(n.wildcards) > 0 && p != "" {
+		if strings.Contains(strings.ToLower(p), "%2f") {
+			return false, path.ErrEncodedSlash
+		}
+		tail, err := url.PathUnescape(p)
+		if err != nil {
+			return false, path.ErrBadEncoding
+		}
+		for _, c := range n.wildcards {
+			if c.check != nil && !c.check(tail) {
+				continue
+			}
+			if c.routes != nil && fn(c, append(vals, tail)) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (n *node) next(rest string, more bool, vals []string, fn func(*node, []string) bool) (bool, error) {
+	if more {
+		return n.visit(rest, vals, fn)
+	}
+	return n.routes != nil && fn(n, vals), nil
+}
+
+// lookup finds the route for r at the escaped path p, using method rather
+// than that of r. It does not allocate for routes without parameters and
+// matchers.
+func (n *node) lookup(method, p string, r *http.Request) (m match, err error) {
+	if p == "/" {
+		n.pick(method, r, &m)
+		return m, nil
+	}
+	_, err = n.visit(p[1:], nil, func(n *node, v []string) bool {
+		m.vals = v
+		return n.pick(method, r, &m)
+	})
+	return m, err
+}
+
+// allowed returns the methods with a route for r at the escaped path p.
+func (n *node) allowed(p string, r *http.Request) []string {
+	var methods []string
+	add := func(n *node) {
+		for _, method := range n.methods {
+			var m match
+			if method != anyMethod && !slices.Contains(methods, method) && n.pick(method, r, &m) {
+				methods = append(methods, method)
+			}
+		}
+	}
+	if p == "/" {
+		add(n)
+		return methods
+	}
+	_, _ = n.visit(p[1:], nil, func(n *node, _ []string) bool {
+		add(n)
+		return false
+	})
+	return methods
+}
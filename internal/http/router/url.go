@@ -0,0 +1,63 @@
+package router
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URL returns the escaped path of the route called name, including the
+// prefixes of its groups. params are pairs of parameter names and values:
+//
+//	router.URL("todo", "id", "0190a5c2-...") // "/api/v1/todos/0190a5c2-..."
+//
+// Every parameter must be given and pass its constraint, except optional
+// ones, which may be left out. A wildcard value can span segments.
+func (router *Router) URL(name string, params ...string) (string, error) {
+	route := router.table().names[name]
+	if route == nil {
+		return "", fmt.Errorf("%w %q", ErrUnknownRoute, name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("%w: odd number of arguments for %q", ErrURLParams, name)
+	}
+	vals := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		vals[params[i]] = params[i+1]
+	}
+
+	var b strings.Builder
+	for _, seg := range route.segs {
+		if !seg.Param && !seg.Wildcard {
+			b.WriteString("/" + url.PathEscape(seg.Value))
+			continue
+		}
+		v, ok := vals[seg.Value]
+		delete(vals, seg.Value)
+		switch {
+		case !ok && seg.Optional:
+			continue
+		case !ok || v == "":
+			return "", fmt.Errorf("%w: %q needs %s", ErrURLParams, name, seg.Value)
+		case seg.Check != nil && !seg.Check(v):
+			return "", fmt.Errorf("%w: %s=%q does not match <%s>", ErrURLParams, seg.Value, v, seg.Constraint)
+		}
+		if !seg.Wildcard {
+			b.WriteString("/" + url.PathEscape(v))
+			continue
+		}
+		for _, part := range strings.Split(strings.Trim(v, "/"), "/") {
+			if part == "" {
+				return "", fmt.Errorf("%w: %s=%q has an empty segment", ErrURLParams, seg.Value, v)
+			}
+			b.WriteString("/" + url.PathEscape(part))
+		}
+	}
+	for k := range vals {
+		return "", fmt.Errorf("%w: %q has no parameter %s", ErrURLParams, name, k)
+	}
+	if b.Len() == 0 {
+		return "/", nil
+	}
+	return b.String(), nil
+}
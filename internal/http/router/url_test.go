@@ -0,0 +1,67 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestRouter_URL(t *testing.T) {
+	r := &Router{}
+	r.Group("/api/v1", func(api *Router) {
+		api.Group("todos", func(todos *Router) {
+			todos.Handle(http.MethodPost, "", http.HandlerFunc(ok), Name("todos"))
+			todos.Handle(http.MethodGet, ":id", http.HandlerFunc(ok), Name("todo"))
+			todos.Handle(http.MethodDelete, ":id", http.HandlerFunc(ok), Name("todo"))
+		})
+		api.Handle(http.MethodGet, "/files/*path", http.HandlerFunc(ok), Name("file"))
+		api.Handle(http.MethodGet, "/items/:n<int>/:tab?", http.HandlerFunc(ok), Name("item"))
+	})
+
+	for _, tc := range []struct {
+		name   string
+		params []string
+		want   string
+	}{
+		{"todos", nil, "/api/v1/todos"},
+		{"todo", []string{"id", "a b/c"}, "/api/v1/todos/a%20b%2Fc"},
+		{"file", []string{"path", "docs/a b.txt"}, "/api/v1/files/docs/a%20b.txt"},
+		{"item", []string{"n", "7"}, "/api/v1/items/7"},
+		{"item", []string{"n", "7", "tab", "notes"}, "/api/v1/items/7/notes"},
+	} {
+		got, err := r.URL(tc.name, tc.params...)
+		if err != nil {
+			t.Fatalf("URL(%q, %q) err = %v", tc.name, tc.params, err)
+		}
+		if got != tc.want {
+			t.Fatalf("URL(%q, %q) = %q, want %q", tc.name, tc.params, got, tc.want)
+		}
+	}
+}
+
+func TestRouter_URL_Errors(t *testing.T) {
+	r := &Router{}
+	r.Handle(http.MethodGet, "/todos/:id", http.HandlerFunc(ok), Name("todo"))
+	r.Handle(http.MethodGet, "/items/:n<int>", http.HandlerFunc(ok), Name("item"))
+
+	for _, tc := range []struct {
+		name   string
+		params []string
+		want   error
+	}{
+		{"nope", nil, ErrUnknownRoute},
+		{"todo", nil, ErrURLParams},
+		{"todo", []string{"id"}, ErrURLParams},
+		{"todo", []string{"id", ""}, ErrURLParams},
+		{"todo", []string{"id", "1", "extra", "2"}, ErrURLParams},
+		{"item", []string{"n", "seven"}, ErrURLParams},
+	} {
+		if _, err := r.URL(tc.name, tc.params...); !errors.Is(err, tc.want) {
+			t.Fatalf("URL(%q, %q) err = %v, want %v", tc.name, tc.params, err, tc.want)
+		}
+	}
+
+	if err := r.Handle(http.MethodGet, "/other", http.HandlerFunc(ok), Name("todo")); !errors.Is(err, ErrDuplicateName) {
+		t.Fatalf("Handle() err = %v, want %v", err, ErrDuplicateName)
+	}
+}
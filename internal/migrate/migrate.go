@@ -0,0 +1,190 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration was modified")
+	ErrUnknownVersion   = errors.New("applied migration is unknown")
+	ErrIrreversible     = errors.New("migration has no down script")
+)
+
+// lockKey identifies the advisory lock taken while migrating,
+// so replicas starting at the same time migrate one after another.
+const lockKey int64 = 0x746f646f5f6d6967 // "todo_mig"
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	ms, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: ms}, nil
+}
+
+type Status struct {
+	Migration
+	Applied bool
+}
+
+// Status reports every known migration and whether it is applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var out []Status
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]string) error {
+		for _, mg := range m.migrations {
+			_, ok := applied[mg.Version]
+			out = append(out, Status{Migration: mg, Applied: ok})
+		}
+		return nil
+	})
+	return out, err
+}
+
+// Up applies all pending migrations in order, each in its own transaction.
+// With dryRun nothing is executed. It returns the migrations (to be) applied.
+func (m *Migrator) Up(ctx context.Context, dryRun bool) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]string) error {
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+			if !dryRun {
+				err := inTx(ctx, conn, mg.Up, `
+	INSERT INTO schema_migrations (version, name, checksum)
+	VALUES($1, $2, $3)
+	`, mg.Version, mg.Name, mg.Checksum)
+				if err != nil {
+					return fmt.Errorf("migration %d_%s: %w", mg.Version, mg.Name, err)
+				}
+				log.Printf("migrate: applied %d_%s", mg.Version, mg.Name)
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the last steps applied migrations, newest first.
+// With dryRun nothing is executed. It returns the migrations (to be) reverted.
+func (m *Migrator) Down(ctx context.Context, steps int, dryRun bool) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]string) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; !ok {
+				continue
+			}
+			if mg.Down == "" {
+				return fmt.Errorf("migration %d_%s: %w", mg.Version, mg.Name, ErrIrreversible)
+			}
+			if !dryRun {
+				err := inTx(ctx, conn, mg.Down, `
+	DELETE FROM schema_migrations
+	WHERE version = $1
+	`, mg.Version)
+				if err != nil {
+					return fmt.Errorf("migration %d_%s: %w", mg.Version, mg.Name, err)
+				}
+				log.Printf("migrate: reverted %d_%s", mg.Version, mg.Name)
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// locked runs fn on a single connection holding the advisory lock, with the
+// applied versions and their checksums already verified against the known ones.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, applied map[int64]string) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if _, err := conn.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)
+	`); err != nil {
+		return err
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, mg := range m.migrations {
+		known[mg.Version] = mg
+	}
+	for version, sum := range applied {
+		mg, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: version %d", ErrUnknownVersion, version)
+		}
+		if mg.Checksum != sum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, mg.Version, mg.Name)
+		}
+	}
+
+	return fn(conn, applied)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]string, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]string)
+	for rows.Next() {
+		var version int64
+		var sum string
+		if err := rows.Scan(&version, &sum); err != nil {
+			return nil, err
+		}
+		applied[version] = sum
+	}
+	return applied, rows.Err()
+}
+
+// inTx runs a migration script and the bookkeeping statement atomically.
+func inTx(ctx context.Context, conn *sql.Conn, script, query string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
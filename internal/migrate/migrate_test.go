@@ -0,0 +1,222 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"todo-api/sql/migrations"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var testFS = fstest.MapFS{
+	"001_init.sql":        {Data: []byte("CREATE TABLE a (id INT);")},
+	"001_init.down.sql":   {Data: []byte("DROP TABLE a;")},
+	"002_second.sql":      {Data: []byte("CREATE TABLE b (id INT);")},
+	"002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+	"003_third.sql":       {Data: []byte("CREATE TABLE c (id INT);")},
+}
+
+func TestLoad_Sorted(t *testing.T) {
+	ms, err := Load(testFS)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(ms) != 3 {
+		t.Fatalf("Load() got %d migrations, want 3", len(ms))
+	}
+	if ms[0].Version != 1 || ms[1].Version != 2 || ms[2].Version != 3 {
+		t.Fatalf("Load() order = %d, %d, %d", ms[0].Version, ms[1].Version, ms[2].Version)
+	}
+	if ms[1].Name != "second" || ms[1].Down != "DROP TABLE b;" || ms[2].Down != "" {
+		t.Fatalf("Load() unexpected migration: %+v", ms[1])
+	}
+	if ms[0].Checksum == "" || ms[0].Checksum == ms[1].Checksum {
+		t.Fatalf("Load() checksums look wrong: %q, %q", ms[0].Checksum, ms[1].Checksum)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for name, fsys := range map[string]fstest.MapFS{
+		"no version": {"init.sql": {Data: []byte("x")}},
+		"down only":  {"001_init.down.sql": {Data: []byte("x")}},
+		"name clash": {"001_a.sql": {Data: []byte("x")}, "001_b.sql": {Data: []byte("y")}},
+	} {
+		if _, err := Load(fsys); !errors.Is(err, ErrInvalidMigration) {
+			t.Fatalf("%s: Load() err = %v, want %v", name, err, ErrInvalidMigration)
+		}
+	}
+}
+
+func newMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock, *Migrator) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	m, err := New(db, testFS)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return db, mock, m
+}
+
+func expectLocked(mock sqlmock.Sqlmock, applied *sqlmock.Rows) {
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).
+		WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, checksum FROM schema_migrations`)).
+		WillReturnRows(applied)
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).
+		WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestUp_AppliesPending(t *testing.T) {
+	db, mock, m := newMock(t)
+	defer db.Close()
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "checksum"}).
+		AddRow(1, m.migrations[0].Checksum))
+	for _, mg := range m.migrations[1:] {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(mg.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO schema_migrations`)).
+			WithArgs(mg.Version, mg.Name, mg.Checksum).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	expectUnlock(mock)
+
+	done, err := m.Up(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if len(done) != 2 || done[0].Version != 2 || done[1].Version != 3 {
+		t.Fatalf("Up() applied %v, want versions 2 and 3", done)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUp_FailureRollsBack(t *testing.T) {
+	db, mock, m := newMock(t)
+	defer db.Close()
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "checksum"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(m.migrations[0].Up)).WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+	expectUnlock(mock)
+
+	done, err := m.Up(context.Background(), false)
+	if err == nil {
+		t.Fatalf("Up() expected error, got nil")
+	}
+	if len(done) != 0 {
+		t.Fatalf("Up() applied %v, want none", done)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUp_DryRun(t *testing.T) {
+	db, mock, m := newMock(t)
+	defer db.Close()
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "checksum"}))
+	expectUnlock(mock)
+
+	done, err := m.Up(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if len(done) != 3 {
+		t.Fatalf("Up() planned %d, want 3", len(done))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUp_ChecksumMismatch(t *testing.T) {
+	db, mock, m := newMock(t)
+	defer db.Close()
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "checksum"}).AddRow(1, "changed"))
+	expectUnlock(mock)
+
+	if _, err := m.Up(context.Background(), false); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Up() err = %v, want %v", err, ErrChecksumMismatch)
+	}
+}
+
+func TestUp_UnknownVersion(t *testing.T) {
+	db, mock, m := newMock(t)
+	defer db.Close()
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "checksum"}).AddRow(42, "x"))
+	expectUnlock(mock)
+
+	if _, err := m.Up(context.Background(), false); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("Up() err = %v, want %v", err, ErrUnknownVersion)
+	}
+}
+
+func TestDown_RevertsNewestFirst(t *testing.T) {
+	db, mock, m := newMock(t)
+	defer db.Close()
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "checksum"}).
+		AddRow(1, m.migrations[0].Checksum).
+		AddRow(2, m.migrations[1].Checksum))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DROP TABLE b;`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM schema_migrations`)).
+		WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUnlock(mock)
+
+	done, err := m.Down(context.Background(), 1, false)
+	if err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if len(done) != 1 || done[0].Version != 2 {
+		t.Fatalf("Down() reverted %v, want version 2", done)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDown_Irreversible(t *testing.T) {
+	db, mock, m := newMock(t)
+	defer db.Close()
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "checksum"}).
+		AddRow(3, m.migrations[2].Checksum))
+	expectUnlock(mock)
+
+	if _, err := m.Down(context.Background(), 1, false); !errors.Is(err, ErrIrreversible) {
+		t.Fatalf("Down() err = %v, want %v", err, ErrIrreversible)
+	}
+}
+
+func TestLoad_Embedded(t *testing.T) {
+	ms, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(ms) == 0 || ms[0].Version != 1 || ms[0].Down == "" {
+		t.Fatalf("Load() unexpected migrations: %+v", ms)
+	}
+}
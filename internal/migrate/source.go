@@ -0,0 +1,76 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidMigration = errors.New("invalid migration")
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string // empty if the migration can't be reverted
+	Checksum string // of Up
+}
+
+// Load reads migrations from the root of fsys.
+// NNN_name.sql is the up script of version NNN, NNN_name.down.sql its down script.
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, name := range names {
+		base := strings.TrimSuffix(name, ".sql")
+		down := strings.HasSuffix(base, ".down")
+		base = strings.TrimSuffix(base, ".down")
+
+		verStr, title, ok := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(verStr, 10, 64)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigration, name)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Clean(name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		}
+		if m.Name != title {
+			return nil, fmt.Errorf("%w: version %d used by %q and %q", ErrInvalidMigration, version, m.Name, title)
+		}
+		if down {
+			m.Down = string(body)
+		} else {
+			m.Up = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%w: version %d has no up script", ErrInvalidMigration, m.Version)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
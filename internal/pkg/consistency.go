@@ -0,0 +1,16 @@
+package pkg
+
+import "context"
+
+type keyReadPrimary struct{}
+
+// WithReadPrimary marks ctx as needing up-to-date reads, so storage must not
+// serve it from a possibly lagging replica.
+func WithReadPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyReadPrimary{}, true)
+}
+
+func ReadPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(keyReadPrimary{}).(bool)
+	return v
+}
@@ -0,0 +1,42 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const lockName = "LOCK"
+
+// ErrLocked is returned by LockDir when another process holds the lock.
+var ErrLocked = errors.New("directory is locked by another process")
+
+// DirLock is an exclusive lock on a data directory, held until Unlock.
+type DirLock struct {
+	f *os.File
+}
+
+// LockDir takes the exclusive lock on dir, so that a single process at a time
+// opens the data in it. It fails with ErrLocked when the lock is held.
+// The lock goes away with the process, a crash does not leave it behind.
+func LockDir(dir string) (*DirLock, error) {
+	f, err := os.OpenFile(filepath.Join(dir, lockName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &DirLock{f: f}, nil
+}
+
+// Unlock releases the lock. It is safe to call more than once.
+func (l *DirLock) Unlock() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package pkg
+
+import "os"
+
+// lockFile does not lock on platforms without flock.
+func lockFile(f *os.File) error {
+	return nil
+}
@@ -3,11 +3,35 @@ package pkg
 import (
 	"context"
 	"net/http"
+	"time"
 )
 
 type Scope struct {
 	RealIP string
 	Params map[string]string
+	Tenant string
+	// Route describes the route serving the request, nil outside the router.
+	Route *RouteMeta
+}
+
+// RouteMeta is what a route declares about itself for middleware to act on,
+// set with the route options of the router. It is shared by all requests to
+// the route and must not be changed.
+type RouteMeta struct {
+	Name    string
+	Method  string
+	Pattern string
+	// Auth lists the requirements a request must meet, such as scopes or
+	// roles, for an authorization middleware to check.
+	Auth []string
+	// RateLimit is the rate limit class of the route, "" for the default.
+	RateLimit string
+	// BodyLimit caps the size of request bodies in bytes, 0 for no limit.
+	BodyLimit int64
+	// Timeout bounds the time spent on a request, 0 for none.
+	Timeout time.Duration
+	// Values holds metadata of other middleware by key.
+	Values map[string]any
 }
 
 type keyScope struct{}
@@ -22,3 +46,12 @@ func ScopeFrom(r *http.Request) *Scope {
 	}
 	return nil
 }
+
+// RouteFrom returns the metadata of the route serving r, nil if there is
+// none.
+func RouteFrom(r *http.Request) *RouteMeta {
+	if s := ScopeFrom(r); s != nil {
+		return s.Route
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package pkg
+
+import (
+	"context"
+	"regexp"
+)
+
+// DefaultTenant owns the data of requests that do not name a tenant.
+const DefaultTenant = "default"
+
+var tenantRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidTenant reports whether id can be used as a tenant id.
+func ValidTenant(id string) bool {
+	return tenantRe.MatchString(id)
+}
+
+type keyTenant struct{}
+
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, keyTenant{}, id)
+}
+
+// TenantFrom returns the tenant ctx belongs to, DefaultTenant if none is set.
+// Storage scopes every operation by it.
+func TenantFrom(ctx context.Context) string {
+	if v, ok := ctx.Value(keyTenant{}).(string); ok && v != "" {
+		return v
+	}
+	return DefaultTenant
+}
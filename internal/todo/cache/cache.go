@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultSize        = 1000
+	defaultTTL         = 30 * time.Second
+	defaultNegativeTTL = 5 * time.Second
+)
+
+type Options struct {
+	Size int
+	TTL  time.Duration
+	// NegativeTTL is how long todo.ErrNotFound is remembered, 0 uses the default.
+	NegativeTTL time.Duration
+}
+
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// Loads counts calls to the wrapped repository, Coalesced the misses
+	// that were served by a load started by another caller.
+	Loads     uint64 `json:"loads"`
+	Coalesced uint64 `json:"coalesced"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+}
+
+// Repository is a read-through cache in front of another todo.Repository.
+// Get results, including todo.ErrNotFound, are cached; concurrent misses for
+// the same id share a single load. Create and Remove update the cache.
+type Repository struct {
+	next  todo.Repository
+	opts  Options
+	group singleflight.Group
+	now   func() time.Time
+
+	mu    sync.Mutex
+	lru   *lru
+	epoch uint64 // bumped on every invalidation
+	stats Stats
+}
+
+var _ todo.Repository = (*Repository)(nil)
+
+func New(next todo.Repository, opts Options) *Repository {
+	if opts.Size <= 0 {
+		opts.Size = defaultSize
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = defaultTTL
+	}
+	if opts.NegativeTTL <= 0 {
+		opts.NegativeTTL = defaultNegativeTTL
+	}
+	return &Repository{
+		next: next,
+		opts: opts,
+		now:  time.Now,
+		lru:  newLRU(opts.Size),
+	}
+}
+
+func (c *Repository) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stats
+	s.Coalesced = s.Misses - s.Loads
+	s.Entries = c.lru.len()
+	return s
+}
+
+// Ping implements todo.Repository.
+func (c *Repository) Ping(ctx context.Context) error {
+	return c.next.Ping(ctx)
+}
+
+// Create implements todo.Repository.
+func (c *Repository) Create(ctx context.Context, t todo.Todo) (todo.Todo, error) {
+	out, err := c.next.Create(ctx, t)
+	if err != nil {
+		return todo.Todo{}, err
+	}
+
+	tenant := pkg.TenantFrom(ctx)
+	expires := c.now().Add(c.opts.TTL)
+	c.mu.Lock()
+	c.epoch++
+	c.stats.Evictions += uint64(c.lru.add(entry{key: key{tenant: tenant, id: out.ID}, todo: out, expires: expires}))
+	c.stats.Evictions += uint64(c.lru.add(entry{key: key{tenant: tenant, publicID: out.PublicID}, todo: out, expires: expires}))
+	c.mu.Unlock()
+	return out, nil
+}
+
+// Remove implements todo.Repository.
+func (c *Repository) Remove(ctx context.Context, id int64) error {
+	err := c.next.Remove(ctx, id)
+	// invalidate even on failure, the outcome may be unknown
+	c.mu.Lock()
+	c.epoch++
+	c.lru.removeTodo(pkg.TenantFrom(ctx), id)
+	c.mu.Unlock()
+	return err
+}
+
+// Get implements todo.Repository.
+func (c *Repository) Get(ctx context.Context, id int64) (todo.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return todo.Todo{}, err
+	}
+
+	k := key{tenant: pkg.TenantFrom(ctx), id: id}
+	c.mu.Lock()
+	if e, ok := c.lru.get(k, c.now()); ok {
+		c.stats.Hits++
+		c.mu.Unlock()
+		if e.notFound {
+			return todo.Todo{}, todo.ErrNotFound
+		}
+		return e.todo, nil
+	}
+	c.stats.Misses++
+	epoch := c.epoch
+	c.mu.Unlock()
+
+	// loads started before an invalidation are not joined by later callers
+	sfKey := k.tenant + "/" + strconv.FormatInt(id, 10) + "@" + strconv.FormatUint(epoch, 10)
+	ch := c.group.DoChan(sfKey, func() (any, error) {
+		c.mu.Lock()
+		c.stats.Loads++
+		c.mu.Unlock()
+
+		lctx, cancel := loadContext(ctx)
+		defer cancel()
+
+		t, err := c.next.Get(lctx, id)
+		c.store(k, t, err, epoch)
+		return t, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return todo.Todo{}, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return todo.Todo{}, res.Err
+		}
+		return res.Val.(todo.Todo), nil
+	}
+}
+
+// Resolve implements todo.Repository. Found mappings are cached until the
+// todo is removed through the cache.
+func (c *Repository) Resolve(ctx context.Context, publicID string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	k := key{tenant: pkg.TenantFrom(ctx), publicID: publicID}
+	c.mu.Lock()
+	if e, ok := c.lru.get(k, c.now()); ok {
+		c.stats.Hits++
+		c.mu.Unlock()
+		return e.todo.ID, nil
+	}
+	c.stats.Misses++
+	c.stats.Loads++
+	c.mu.Unlock()
+
+	id, err := c.next.Resolve(ctx, publicID)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	c.stats.Evictions += uint64(c.lru.add(entry{key: k, todo: todo.Todo{ID: id}, expires: c.now().Add(c.opts.TTL)}))
+	c.mu.Unlock()
+	return id, nil
+}
+
+// loadContext detaches a shared load from the cancellation of the caller that
+// started it, the others are still waiting for it. The deadline is kept.
+func loadContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	lctx := context.WithoutCancel(ctx)
+	if dl, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(lctx, dl)
+	}
+	return lctx, func() {}
+}
+
+// store caches a load result unless the cache was invalidated meanwhile.
+func (c *Repository) store(k key, t todo.Todo, err error, epoch uint64) {
+	e := entry{key: k, todo: t}
+	switch {
+	case err == nil:
+		e.expires = c.now().Add(c.opts.TTL)
+	case errors.Is(err, todo.ErrNotFound):
+		e.notFound = true
+		e.expires = c.now().Add(c.opts.NegativeTTL)
+	default:
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.epoch != epoch {
+		return
+	}
+	c.stats.Evictions += uint64(c.lru.add(e))
+}
@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"todo-api/internal/todo"
+	"todo-api/internal/todo/repotest"
+	"todo-api/internal/todo/storagemem"
+)
+
+// countingRepo counts Get calls and can hold them until release is closed.
+type countingRepo struct {
+	todo.Repository
+	gets    atomic.Int64
+	release chan struct{}
+}
+
+func (r *countingRepo) Get(ctx context.Context, id int64) (todo.Todo, error) {
+	r.gets.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+	return r.Repository.Get(ctx, id)
+}
+
+func newCache(opts Options) (*Repository, *countingRepo) {
+	next := &countingRepo{Repository: storagemem.NewInMemoryStore()}
+	return New(next, opts), next
+}
+
+func TestContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) todo.Repository {
+		return New(storagemem.NewInMemoryStore(), Options{})
+	})
+}
+
+func TestGet_Hit(t *testing.T) {
+	c, next := newCache(Options{})
+	ctx := context.Background()
+
+	out, _ := next.Create(ctx, todo.Todo{Title: "t"})
+	for i := 0; i < 3; i++ {
+		got, err := c.Get(ctx, out.ID)
+		if err != nil || got.ID != out.ID {
+			t.Fatalf("Get() = %v, %v", got, err)
+		}
+	}
+
+	if n := next.gets.Load(); n != 1 {
+		t.Fatalf("backend Get calls = %d, want 1", n)
+	}
+	if s := c.Stats(); s.Hits != 2 || s.Misses != 1 || s.Entries != 1 {
+		t.Fatalf("Stats() = %+v, want 2 hits, 1 miss, 1 entry", s)
+	}
+}
+
+func TestGet_TTL(t *testing.T) {
+	c, next := newCache(Options{TTL: time.Minute})
+	ctx := context.Background()
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	out, _ := next.Create(ctx, todo.Todo{Title: "t"})
+	c.Get(ctx, out.ID)
+	now = now.Add(2 * time.Minute)
+	c.Get(ctx, out.ID)
+
+	if n := next.gets.Load(); n != 2 {
+		t.Fatalf("backend Get calls = %d, want 2", n)
+	}
+}
+
+func TestGet_NegativeCaching(t *testing.T) {
+	c, next := newCache(Options{NegativeTTL: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get(ctx, 42); !errors.Is(err, todo.ErrNotFound) {
+			t.Fatalf("Get() err = %v, want %v", err, todo.ErrNotFound)
+		}
+	}
+	if n := next.gets.Load(); n != 1 {
+		t.Fatalf("backend Get calls = %d, want 1", n)
+	}
+}
+
+func TestCreate_ReplacesNegativeEntry(t *testing.T) {
+	c, _ := newCache(Options{NegativeTTL: time.Minute})
+	ctx := context.Background()
+
+	if _, err := c.Get(ctx, 1); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Get() err = %v, want %v", err, todo.ErrNotFound)
+	}
+	out, err := c.Create(ctx, todo.Todo{Title: "t"})
+	if err != nil || out.ID != 1 {
+		t.Fatalf("Create() = %v, %v; want id 1", out, err)
+	}
+	if got, err := c.Get(ctx, 1); err != nil || got.Title != "t" {
+		t.Fatalf("Get() = %v, %v; want created todo", got, err)
+	}
+}
+
+func TestRemove_Invalidates(t *testing.T) {
+	c, _ := newCache(Options{})
+	ctx := context.Background()
+
+	out, _ := c.Create(ctx, todo.Todo{Title: "t"})
+	c.Get(ctx, out.ID)
+	if err := c.Remove(ctx, out.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := c.Get(ctx, out.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Get() err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if _, err := c.Resolve(ctx, out.PublicID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Resolve() err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if n := len(c.lru.publicIDs); n != 0 {
+		t.Fatalf("publicIDs = %d entries, want 0", n)
+	}
+}
+
+func TestLRU_Eviction(t *testing.T) {
+	c, next := newCache(Options{Size: 2})
+	ctx := context.Background()
+
+	a, _ := next.Create(ctx, todo.Todo{Title: "a"})
+	b, _ := next.Create(ctx, todo.Todo{Title: "b"})
+	d, _ := next.Create(ctx, todo.Todo{Title: "d"})
+
+	c.Get(ctx, a.ID)
+	c.Get(ctx, b.ID)
+	c.Get(ctx, a.ID) // a is now more recent than b
+	c.Get(ctx, d.ID) // evicts b
+
+	next.gets.Store(0)
+	c.Get(ctx, a.ID)
+	c.Get(ctx, b.ID)
+	if n := next.gets.Load(); n != 1 {
+		t.Fatalf("backend Get calls = %d, want 1 (only b)", n)
+	}
+	if s := c.Stats(); s.Evictions < 1 || s.Entries != 2 {
+		t.Fatalf("Stats() = %+v, want evictions and 2 entries", s)
+	}
+}
+
+func TestGet_CoalescesConcurrentMisses(t *testing.T) {
+	c, next := newCache(Options{})
+	ctx := context.Background()
+	out, _ := next.Create(ctx, todo.Todo{Title: "t"})
+	next.release = make(chan struct{})
+
+	const n = 10
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Get(ctx, out.ID); err != nil {
+				t.Errorf("Get() error = %v", err)
+			}
+		}()
+	}
+
+	// wait until every caller is either loading or waiting for the load
+	for c.Stats().Misses < n {
+		time.Sleep(time.Millisecond)
+	}
+	close(next.release)
+	wg.Wait()
+
+	if got := next.gets.Load(); got != 1 {
+		t.Fatalf("backend Get calls = %d, want 1", got)
+	}
+	if s := c.Stats(); s.Coalesced != n-1 {
+		t.Fatalf("Stats().Coalesced = %d, want %d", s.Coalesced, n-1)
+	}
+}
+
+func TestGet_StaleLoadNotStored(t *testing.T) {
+	c, next := newCache(Options{})
+	ctx := context.Background()
+	out, _ := next.Create(ctx, todo.Todo{Title: "t"})
+	next.release = make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Get(ctx, out.ID)
+	}()
+	for next.gets.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// removed while the load is in flight
+	c.mu.Lock()
+	c.epoch++
+	c.mu.Unlock()
+	close(next.release)
+	<-done
+
+	if s := c.Stats(); s.Entries != 0 {
+		t.Fatalf("Stats().Entries = %d, want 0", s.Entries)
+	}
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"container/list"
+	"time"
+	"todo-api/internal/todo"
+)
+
+// key identifies a todo, ids are only unique within a tenant.
+// Entries keyed by publicID map it to the id, which never changes.
+type key struct {
+	tenant   string
+	id       int64
+	publicID string
+}
+
+type entry struct {
+	key      key
+	todo     todo.Todo
+	notFound bool
+	expires  time.Time
+}
+
+// lru is a size-bounded map with least-recently-used eviction.
+// It is not safe for concurrent use.
+type lru struct {
+	size  int
+	ll    *list.List
+	items map[key]*list.Element
+	// publicIDs maps the id key of a todo to the key of its publicID entry.
+	publicIDs map[key]key
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:      size,
+		ll:        list.New(),
+		items:     make(map[key]*list.Element),
+		publicIDs: make(map[key]key),
+	}
+}
+
+// idKey returns the id key of the todo of a publicID entry.
+func (e entry) idKey() key {
+	return key{tenant: e.key.tenant, id: e.todo.ID}
+}
+
+// get returns the entry for k unless it is missing or expired at now.
+func (c *lru) get(k key, now time.Time) (entry, bool) {
+	el, ok := c.items[k]
+	if !ok {
+		return entry{}, false
+	}
+	e := el.Value.(entry)
+	if !now.Before(e.expires) {
+		c.delete(el)
+		return entry{}, false
+	}
+	c.ll.MoveToFront(el)
+	return e, true
+}
+
+// add stores e and returns the number of evicted entries.
+func (c *lru) add(e entry) int {
+	if el, ok := c.items[e.key]; ok {
+		el.Value = e
+		c.ll.MoveToFront(el)
+		return 0
+	}
+
+	c.items[e.key] = c.ll.PushFront(e)
+	if e.key.publicID != "" {
+		c.publicIDs[e.idKey()] = e.key
+	}
+	evicted := 0
+	for c.ll.Len() > c.size {
+		c.delete(c.ll.Back())
+		evicted++
+	}
+	return evicted
+}
+
+func (c *lru) remove(k key) {
+	if el, ok := c.items[k]; ok {
+		c.delete(el)
+	}
+}
+
+// removeTodo removes the entries of the todo id of tenant, by id and by
+// publicID.
+func (c *lru) removeTodo(tenant string, id int64) {
+	k := key{tenant: tenant, id: id}
+	c.remove(k)
+	if pk, ok := c.publicIDs[k]; ok {
+		c.remove(pk)
+	}
+}
+
+func (c *lru) delete(el *list.Element) {
+	e := el.Value.(entry)
+	c.ll.Remove(el)
+	delete(c.items, e.key)
+	if e.key.publicID != "" && c.publicIDs[e.idKey()] == e.key {
+		delete(c.publicIDs, e.idKey())
+	}
+}
+
+func (c *lru) len() int {
+	return c.ll.Len()
+}
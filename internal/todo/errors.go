@@ -1,5 +1,18 @@
 package todo
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var ErrNotFound = errors.New("not found")
+
+// ErrUnavailable means the storage refuses requests for a while,
+// e.g. because a circuit breaker is open.
+var ErrUnavailable = errors.New("storage unavailable")
+
+// RetryAfterError is implemented by errors that know when to try again.
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
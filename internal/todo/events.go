@@ -0,0 +1,12 @@
+package todo
+
+// Domain event types emitted on every successful mutation of a todo.
+const (
+	EventCreated = "todo.created"
+	EventRemoved = "todo.removed"
+)
+
+// RemovedPayload is the body of an EventRemoved event.
+type RemovedPayload struct {
+	ID string `json:"id"` // public id
+}
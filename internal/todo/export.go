@@ -0,0 +1,27 @@
+package todo
+
+import "context"
+
+// Record is a unit of a store's data as seen by backups: either the id
+// counter of a tenant or one of its todos.
+type Record struct {
+	Tenant string
+	// LastID is set on counter records, Todo on todo records.
+	LastID int64
+	Todo   *Todo
+}
+
+// Exporter is implemented by stores whose whole content can be read out.
+type Exporter interface {
+	// Export calls fn for every record of every tenant, reading the store at
+	// a single point in time. A tenant's counter comes before its todos and
+	// todos come ordered by tenant, then id.
+	Export(ctx context.Context, fn func(Record) error) error
+}
+
+// Importer is implemented by stores that can load exported records.
+type Importer interface {
+	// Import stores r as is, keeping ids, public ids and timestamps.
+	// A todo with an existing id is replaced, counters never go back.
+	Import(ctx context.Context, r Record) error
+}
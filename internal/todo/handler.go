@@ -5,55 +5,87 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"math"
 	"net/http"
 	"strconv"
 	httpx "todo-api/internal/http"
-	"todo-api/internal/pkg"
+	"todo-api/internal/http/bind"
 )
 
 type TodoCreateRequest struct {
-	Title       string  `json:"title"`
+	Title       string  `json:"title" validate:"trim,required,max=140"`
 	Description *string `json:"description"`
 }
 
-type Handler struct {
-	repo Repository
+// todoRequest names a todo by the id in its URL.
+type todoRequest struct {
+	ID string `path:"id" validate:"required"`
 }
 
-func NewHandler(repo Repository) *Handler {
-	return &Handler{repo: repo}
+// Names of the routes the handler links to.
+const (
+	RouteTodos = "todos"
+	RouteTodo  = "todo"
+)
+
+// URLBuilder builds the path of a named route, see router.Router.URL.
+type URLBuilder interface {
+	URL(name string, params ...string) (string, error)
 }
 
-func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+type Handler struct {
+	repo       Repository
+	numericIDs bool
+	urls       URLBuilder
+}
 
-	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MB
+type HandlerOption func(*Handler)
 
-	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
-	var in TodoCreateRequest
+// WithNumericIDs makes the handler accept the internal numeric ids next to
+// the public ones, for clients written before public ids existed.
+func WithNumericIDs() HandlerOption {
+	return func(h *Handler) {
+		h.numericIDs = true
+	}
+}
 
-	if r.Header.Get("Content-Type") != "application/json" {
-		httpx.WriteError(w,
-			http.StatusUnsupportedMediaType,
-			"unsupported_media_type",
-			"expect application/json")
+// WithURLs makes the handler link its responses to the RouteTodos and
+// RouteTodo routes of urls, with a Location header after Create.
+func WithURLs(urls URLBuilder) HandlerOption {
+	return func(h *Handler) {
+		h.urls = urls
 	}
+}
 
-	if err := dec.Decode(&in); err != nil {
-		httpx.WriteError(w, http.StatusBadRequest, "invalid_json", "unable to process json")
-		return
+func NewHandler(repo Repository, opts ...HandlerOption) *Handler {
+	h := &Handler{repo: repo}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
+}
 
-	if dec.Decode(&struct{}{}) != io.EOF {
-		httpx.WriteError(w,
-			http.StatusBadRequest,
-			"invalid_json",
-			"multiple json values")
+var errInvalidID = errors.New("invalid todo id")
+
+// todoID turns the id of a URL into the internal id of a todo.
+func (h *Handler) todoID(r *http.Request, idStr string) (int64, error) {
+	if ValidPublicID(idStr) {
+		return h.repo.Resolve(r.Context(), idStr)
+	}
+	if !h.numericIDs {
+		return 0, errInvalidID
 	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errInvalidID
+	}
+	return id, nil
+}
 
-	if err := validate(&in); err != nil {
-		httpx.WriteError(w, http.StatusUnprocessableEntity, "invalid_content", "unable to handle data")
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var in TodoCreateRequest
+	if err := bind.Bind(r, &in); err != nil {
+		bind.WriteError(w, err)
 		return
 	}
 
@@ -64,35 +96,64 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 
 	out, err := h.repo.Create(r.Context(), t)
 	if err != nil {
-		httpx.WriteError(w, http.StatusInternalServerError, "storage_error", "internal server error")
+		writeStorageError(w, err)
 		return
 	}
 
+	dto := h.dto(out)
 	w.Header().Set("Content-Type", "application/json")
+	if self, ok := dto.Links["self"]; ok {
+		w.Header().Set("Location", self)
+	}
 	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(ToDTO(out))
+	_ = json.NewEncoder(w).Encode(dto)
 }
 
-func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
-	scope := pkg.ScopeFrom(r)
-	if scope == nil {
-		httpx.WriteError(w, http.StatusBadRequest, "invalid_parameters", "invalid request parameters")
-		return
+// dto is ToDTO with the links of t, as far as their routes are known.
+func (h *Handler) dto(t Todo) TodoDTO {
+	dto := ToDTO(t)
+	if h.urls == nil {
+		return dto
+	}
+	links := make(map[string]string, 2)
+	if u, err := h.urls.URL(RouteTodo, "id", t.PublicID); err == nil {
+		links["self"] = u
+	}
+	if u, err := h.urls.URL(RouteTodos); err == nil {
+		links["collection"] = u
+	}
+	if len(links) > 0 {
+		dto.Links = links
 	}
+	return dto
+}
 
-	idStr, ok := scope.Params["id"]
-	if !ok {
-		httpx.WriteError(w, http.StatusBadRequest, "invalid_id", "unable to get id")
-		return
+// bindID returns the internal id of the todo r is about, it writes the error
+// response when there is none.
+func (h *Handler) bindID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	var in todoRequest
+	if err := bind.Bind(r, &in); err != nil {
+		bind.WriteError(w, err)
+		return 0, false
 	}
 
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
+	id, err := h.todoID(r, in.ID)
+	switch {
+	case errors.Is(err, errInvalidID):
 		httpx.WriteError(w, http.StatusBadRequest, "invalid_id", "invalid todo id")
-		return
+	case errors.Is(err, ErrNotFound):
+		httpx.WriteError(w, http.StatusNotFound, "todo_not_found", "todo not found")
+	case err != nil:
+		writeStorageError(w, err)
+	default:
+		return id, true
 	}
-	if id <= 0 {
-		httpx.WriteError(w, http.StatusBadRequest, "invalid_id", "positive id required")
+	return 0, false
+}
+
+func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.bindID(w, r)
+	if !ok {
 		return
 	}
 
@@ -101,12 +162,12 @@ func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
 		httpx.WriteError(w, http.StatusNotFound, "todo_not_found", "todo not found")
 		return
 	} else if err != nil {
-		httpx.WriteError(w, http.StatusInternalServerError, "storage_error", "internal server error")
+		writeStorageError(w, err)
 		return
 	}
 
 	buf := bytes.Buffer{}
-	err = json.NewEncoder(&buf).Encode(ToDTO(todo))
+	err = json.NewEncoder(&buf).Encode(h.dto(todo))
 
 	if err != nil {
 		httpx.WriteError(w, http.StatusInternalServerError, "encoding_error", "internal server error")
@@ -118,37 +179,45 @@ func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) RemoveById(w http.ResponseWriter, r *http.Request) {
-	scope := pkg.ScopeFrom(r)
-	if scope == nil || scope.Params == nil {
-		httpx.WriteError(w, http.StatusBadRequest, "invalid_parameters", "invalid_request_parameters")
-		return
-	}
-
-	idStr, ok := scope.Params["id"]
+	id, ok := h.bindID(w, r)
 	if !ok {
-		httpx.WriteError(w, http.StatusBadRequest, "id_required", "unable to get id")
 		return
 	}
 
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		httpx.WriteError(w, http.StatusBadRequest, "invalid_id", "invalid todo id")
-		return
-	}
-
-	if err := h.repo.Remove(r.Context(), id); errors.Is(err, ErrNotFound) {
+	err := h.repo.Remove(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
 		httpx.WriteError(w, http.StatusNotFound, "todo_not_found", "todo not found")
 		return
 	} else if err != nil {
-		httpx.WriteError(w,
-			http.StatusInternalServerError,
-			"storage_error",
-			http.StatusText(http.StatusInternalServerError))
+		writeStorageError(w, err)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// writeStorageError maps a repository failure to a response. Unavailable
+// storage is a 503 with Retry-After when the error knows how long to wait.
+func writeStorageError(w http.ResponseWriter, err error) {
+	if !errors.Is(err, ErrUnavailable) {
+		httpx.WriteError(w, http.StatusInternalServerError, "storage_error", "internal server error")
+		return
+	}
+
+	SetRetryAfter(w.Header(), err)
+	httpx.WriteError(w, http.StatusServiceUnavailable, "storage_unavailable", "storage temporarily unavailable")
+}
+
+// SetRetryAfter sets the Retry-After header in whole seconds, at least one,
+// when err is a RetryAfterError.
+func SetRetryAfter(h http.Header, err error) {
+	var ra RetryAfterError
+	if errors.As(err, &ra) {
+		secs := int(math.Ceil(ra.RetryAfter().Seconds()))
+		h.Set("Retry-After", strconv.Itoa(max(secs, 1)))
+	}
+}
+
 func HelloMessage(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "Welcome to my website")
 }
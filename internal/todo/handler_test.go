@@ -2,12 +2,14 @@ package todo_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
+	"todo-api/internal/http/router"
 	"todo-api/internal/pkg"
 	"todo-api/internal/todo"
 	"todo-api/internal/todo/storagemem"
@@ -105,8 +107,9 @@ func TestGetTodo_NoItem_404(t *testing.T) {
 	store := storagemem.NewInMemoryStore()
 	h := todo.NewHandler(store)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/todos/123", nil)
-	params := map[string]string{"id": "123"}
+	unknown := todo.NewPublicID()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/todos/"+unknown, nil)
+	params := map[string]string{"id": unknown}
 	scope := &pkg.Scope{}
 	scope.Params = params
 	req = pkg.WithScope(req, scope)
@@ -147,7 +150,10 @@ func TestGetTodo_SetGet_OK(t *testing.T) {
 		t.Fatalf("title=%q, want %q", resp.Title, "Buy milk")
 	}
 
-	idStr := strconv.FormatInt(resp.ID, 10)
+	idStr := resp.ID
+	if !todo.ValidPublicID(idStr) {
+		t.Fatalf("id=%q, want a public id", idStr)
+	}
 
 	req = httptest.NewRequest(http.MethodGet, "/api/v1/todos/"+idStr, nil)
 	params := map[string]string{"id": idStr}
@@ -170,3 +176,62 @@ func TestGetTodo_SetGet_OK(t *testing.T) {
 		t.Fatalf("title=%q, want %q", resp.Title, "Buy milk")
 	}
 }
+
+func getByID(h *todo.Handler, id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/todos/"+id, nil)
+	req = pkg.WithScope(req, &pkg.Scope{Params: map[string]string{"id": id}})
+	rr := httptest.NewRecorder()
+	h.GetByID(rr, req)
+	return rr
+}
+
+func TestGetTodo_NumericID(t *testing.T) {
+	store := storagemem.NewInMemoryStore()
+	out, err := store.Create(context.Background(), todo.Todo{Title: "Buy milk"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	idStr := strconv.FormatInt(out.ID, 10)
+
+	if rr := getByID(todo.NewHandler(store), idStr); rr.Code != http.StatusBadRequest {
+		t.Fatalf("status=%d, want 400", rr.Code)
+	}
+	if rr := getByID(todo.NewHandler(store, todo.WithNumericIDs()), idStr); rr.Code != http.StatusOK {
+		t.Fatalf("status=%d with numeric ids, want 200", rr.Code)
+	}
+	if rr := getByID(todo.NewHandler(store, todo.WithNumericIDs()), "0"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("status=%d for id 0, want 400", rr.Code)
+	}
+}
+
+func TestCreateTodo_Links(t *testing.T) {
+	mux := &router.Router{}
+	h := todo.NewHandler(storagemem.NewInMemoryStore(), todo.WithURLs(mux))
+	mux.Group("/api/v1/todos", func(todos *router.Router) {
+		todos.Handle(http.MethodPost, "", http.HandlerFunc(h.Create), router.Name(todo.RouteTodos))
+		todos.Handle(http.MethodGet, ":id", http.HandlerFunc(h.GetByID), router.Name(todo.RouteTodo))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/todos", strings.NewReader(`{"title":"Buy milk"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	resp := todo.TodoDTO{}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	self := "/api/v1/todos/" + resp.ID
+	if loc := rr.Header().Get("Location"); loc != self {
+		t.Fatalf("Location=%q, want %q", loc, self)
+	}
+	if resp.Links["self"] != self || resp.Links["collection"] != "/api/v1/todos" {
+		t.Fatalf("links=%v", resp.Links)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, self, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET Location status=%d, want 200", rr.Code)
+	}
+}
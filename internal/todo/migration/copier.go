@@ -0,0 +1,145 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+)
+
+const defaultBatchSize = 500
+
+// Checkpoint is the last todo a Copier copied.
+type Checkpoint struct {
+	Tenant string `json:"tenant"`
+	ID     int64  `json:"id"`
+	Done   bool   `json:"done"`
+}
+
+// covers reports whether the todo id of tenant was copied before c was taken,
+// exports list todos ordered by tenant and id.
+func (c Checkpoint) covers(tenant string, id int64) bool {
+	return c.Done || tenant < c.Tenant || tenant == c.Tenant && id <= c.ID
+}
+
+// Checkpoints keeps the progress of a Copier across restarts.
+type Checkpoints interface {
+	Load() (Checkpoint, error)
+	Save(Checkpoint) error
+}
+
+// FileCheckpoints keeps the checkpoint in a JSON file at the given path.
+type FileCheckpoints string
+
+// Load implements Checkpoints. A missing file is an empty checkpoint.
+func (f FileCheckpoints) Load() (Checkpoint, error) {
+	cp := Checkpoint{}
+	b, err := os.ReadFile(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	return cp, json.Unmarshal(b, &cp)
+}
+
+// Save implements Checkpoints. The file is replaced atomically.
+func (f FileCheckpoints) Save(cp Checkpoint) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(string(f)), 0o755); err != nil {
+		return err
+	}
+	return pkg.WriteFileAtomic(string(f), b)
+}
+
+// CopyProgress counts what a Copier did so far.
+type CopyProgress struct {
+	Copied  int64 `json:"copied"`
+	Skipped int64 `json:"skipped"` // copied by an earlier run
+	Done    bool  `json:"done"`
+}
+
+// Copier backfills the todos of one backend into another. Run it while a
+// Repository mirrors writes, so nothing written in the meantime is missed.
+//
+// A todo removed while it is being copied can come back in the destination,
+// a Verify with repair after the copy removes it.
+type Copier struct {
+	From todo.Exporter
+	To   todo.Importer
+	// Checkpoints, if set, lets a restarted copy skip what was copied.
+	Checkpoints Checkpoints
+	// BatchSize is the number of todos between two checkpoints.
+	BatchSize int
+
+	copied  atomic.Int64
+	skipped atomic.Int64
+	done    atomic.Bool
+}
+
+func (c *Copier) Progress() CopyProgress {
+	return CopyProgress{Copied: c.copied.Load(), Skipped: c.skipped.Load(), Done: c.done.Load()}
+}
+
+// Run copies every record that is not covered by the checkpoint yet.
+// Counters are always copied, importing them twice is harmless.
+func (c *Copier) Run(ctx context.Context) error {
+	size := c.BatchSize
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+
+	cp := Checkpoint{}
+	if c.Checkpoints != nil {
+		var err error
+		if cp, err = c.Checkpoints.Load(); err != nil {
+			return err
+		}
+	}
+	if cp.Done {
+		c.done.Store(true)
+		return nil
+	}
+
+	var batch int
+	err := c.From.Export(ctx, func(r todo.Record) error {
+		if r.Todo != nil && cp.covers(r.Tenant, r.Todo.ID) {
+			c.skipped.Add(1)
+			return nil
+		}
+		if err := c.To.Import(ctx, r); err != nil {
+			return err
+		}
+		if r.Todo == nil {
+			return nil
+		}
+
+		c.copied.Add(1)
+		cp.Tenant, cp.ID = r.Tenant, r.Todo.ID
+		if batch++; batch >= size && c.Checkpoints != nil {
+			batch = 0
+			return c.Checkpoints.Save(cp)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cp.Done = true
+	if c.Checkpoints != nil {
+		if err := c.Checkpoints.Save(cp); err != nil {
+			return err
+		}
+	}
+	c.done.Store(true)
+	return nil
+}
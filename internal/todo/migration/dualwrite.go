@@ -0,0 +1,142 @@
+// Package migration moves a live deployment from one storage backend to
+// another without downtime.
+//
+// A Repository writes to both backends while a Copier backfills the todos
+// that existed before. Verify compares both backends, Repair fixes what it
+// finds, and Cutover makes the new backend the one that is read and written
+// first, all while the server keeps running.
+package migration
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+)
+
+// Store is a backend taking part in a migration.
+type Store interface {
+	todo.Repository
+	todo.Exporter
+	todo.Importer
+}
+
+// Repository writes to an old and a new backend. Reads and the id of a new
+// todo come from the primary, the old backend until Cutover. Every write is
+// mirrored to the secondary with the same ids, a failed mirror is logged and
+// counted but does not fail the call; Verify finds it later.
+type Repository struct {
+	// mu is held for reading by writes, so Cutover waits for the writes in
+	// flight and no id is handed out by both backends.
+	mu           sync.RWMutex
+	old, new     Store
+	cutover      atomic.Bool
+	mirrorErrors atomic.Int64
+}
+
+var _ todo.Repository = (*Repository)(nil)
+
+func New(old, new Store) *Repository {
+	return &Repository{old: old, new: new}
+}
+
+// Stats describes the state of a migration.
+type Stats struct {
+	CutOver      bool  `json:"cut_over"`
+	MirrorErrors int64 `json:"mirror_errors"`
+}
+
+func (r *Repository) Stats() Stats {
+	return Stats{CutOver: r.cutover.Load(), MirrorErrors: r.mirrorErrors.Load()}
+}
+
+// Cutover makes the new backend the primary.
+func (r *Repository) Cutover() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cutover.Store(true)
+}
+
+// Rollback makes the old backend the primary again. It is up to date as
+// long as writes were mirrored to it.
+func (r *Repository) Rollback() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cutover.Store(false)
+}
+
+// sides returns the primary and the secondary backend.
+func (r *Repository) sides() (Store, Store) {
+	if r.cutover.Load() {
+		return r.new, r.old
+	}
+	return r.old, r.new
+}
+
+func (r *Repository) mirrored(op string, err error) {
+	if err != nil {
+		r.mirrorErrors.Add(1)
+		log.Printf("migration: mirroring %s failed: %s", op, err)
+	}
+}
+
+// Create implements todo.Repository.
+func (r *Repository) Create(ctx context.Context, t todo.Todo) (todo.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	primary, secondary := r.sides()
+	t, err := primary.Create(ctx, t)
+	if err != nil {
+		return todo.Todo{}, err
+	}
+	r.mirrored("create", secondary.Import(ctx, todo.Record{Tenant: pkg.TenantFrom(ctx), Todo: &t}))
+	return t, nil
+}
+
+// Get implements todo.Repository.
+func (r *Repository) Get(ctx context.Context, id int64) (todo.Todo, error) {
+	primary, _ := r.sides()
+	return primary.Get(ctx, id)
+}
+
+// Resolve implements todo.Repository.
+func (r *Repository) Resolve(ctx context.Context, publicID string) (int64, error) {
+	primary, _ := r.sides()
+	return primary.Resolve(ctx, publicID)
+}
+
+// Remove implements todo.Repository. The secondary may not have the todo
+// yet when the copy is still running.
+func (r *Repository) Remove(ctx context.Context, id int64) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	primary, secondary := r.sides()
+	if err := primary.Remove(ctx, id); err != nil {
+		return err
+	}
+	if err := secondary.Remove(ctx, id); !errors.Is(err, todo.ErrNotFound) {
+		r.mirrored("remove", err)
+	}
+	return nil
+}
+
+// Ping implements todo.Repository.
+func (r *Repository) Ping(ctx context.Context) error {
+	primary, _ := r.sides()
+	return primary.Ping(ctx)
+}
+
+// Verify compares the secondary backend against the primary and, with
+// repair, makes the secondary match it.
+func (r *Repository) Verify(ctx context.Context, repair bool) (Report, error) {
+	primary, secondary := r.sides()
+	if repair {
+		return VerifyRepair(ctx, primary, secondary)
+	}
+	return Verify(ctx, primary, secondary)
+}
@@ -0,0 +1,200 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+	"todo-api/internal/todo/storagemem"
+)
+
+func create(t *testing.T, repo todo.Repository, ctx context.Context, title string) todo.Todo {
+	t.Helper()
+
+	out, err := repo.Create(ctx, todo.Todo{Title: title})
+	if err != nil {
+		t.Fatalf("Create() err = %v", err)
+	}
+	return out
+}
+
+func verifyClean(t *testing.T, source, target todo.Exporter) {
+	t.Helper()
+
+	rep, err := Verify(context.Background(), source, target)
+	if err != nil {
+		t.Fatalf("Verify() err = %v", err)
+	}
+	if rep.Divergent != 0 {
+		t.Fatalf("Verify() divergences = %+v", rep.Divergences)
+	}
+}
+
+func TestRepository_MirrorsWrites(t *testing.T) {
+	old, new := storagemem.NewInMemoryStore(), storagemem.NewInMemoryStore()
+	repo := New(old, new)
+	ctx := pkg.WithTenant(context.Background(), "acme")
+
+	a := create(t, repo, ctx, "a")
+	b := create(t, repo, ctx, "b")
+	if err := repo.Remove(ctx, a.ID); err != nil {
+		t.Fatalf("Remove() err = %v", err)
+	}
+
+	got, err := new.Get(ctx, b.ID)
+	if err != nil {
+		t.Fatalf("new.Get() err = %v", err)
+	}
+	if got != b {
+		t.Fatalf("new.Get() = %v, want %v", got, b)
+	}
+	if _, err := new.Get(ctx, a.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("new.Get() err = %v, want %v", err, todo.ErrNotFound)
+	}
+	verifyClean(t, old, new)
+}
+
+func TestRepository_Cutover(t *testing.T) {
+	old, new := storagemem.NewInMemoryStore(), storagemem.NewInMemoryStore()
+	repo := New(old, new)
+	ctx := context.Background()
+
+	a := create(t, repo, ctx, "a")
+	repo.Cutover()
+	if !repo.Stats().CutOver {
+		t.Fatalf("Stats().CutOver = false after Cutover")
+	}
+
+	// reads and ids now come from the new backend, the old one follows
+	b := create(t, repo, ctx, "b")
+	if b.ID != a.ID+1 {
+		t.Fatalf("Create() id = %d, want %d", b.ID, a.ID+1)
+	}
+	if err := old.Remove(ctx, b.ID); err != nil {
+		t.Fatalf("old.Remove() err = %v", err)
+	}
+	if _, err := repo.Get(ctx, b.ID); err != nil {
+		t.Fatalf("Get() err = %v", err)
+	}
+
+	repo.Rollback()
+	if _, err := repo.Get(ctx, b.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Get() err = %v, want %v", err, todo.ErrNotFound)
+	}
+}
+
+// failingImporter fails every import after the first n.
+type failingImporter struct {
+	todo.Importer
+	n int
+}
+
+func (f *failingImporter) Import(ctx context.Context, r todo.Record) error {
+	if f.n <= 0 {
+		return errors.New("import failed")
+	}
+	f.n--
+	return f.Importer.Import(ctx, r)
+}
+
+func TestCopier_ResumesFromCheckpoint(t *testing.T) {
+	old, new := storagemem.NewInMemoryStore(), storagemem.NewInMemoryStore()
+	for _, tenant := range []string{"acme", "globex"} {
+		ctx := pkg.WithTenant(context.Background(), tenant)
+		for range 5 {
+			create(t, old, ctx, "t")
+		}
+	}
+	cps := FileCheckpoints(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+	// a counter and three todos make it, the checkpoint is at the second
+	c := &Copier{From: old, To: &failingImporter{Importer: new, n: 4}, Checkpoints: cps, BatchSize: 2}
+	if err := c.Run(context.Background()); err == nil {
+		t.Fatalf("Run() expected error")
+	}
+
+	c = &Copier{From: old, To: new, Checkpoints: cps, BatchSize: 2}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run() err = %v", err)
+	}
+	if got, want := c.Progress(), (CopyProgress{Copied: 8, Skipped: 2, Done: true}); got != want {
+		t.Fatalf("Progress() = %+v, want %+v", got, want)
+	}
+	verifyClean(t, old, new)
+
+	c = &Copier{From: old, To: &failingImporter{Importer: new}, Checkpoints: cps}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run() after done err = %v", err)
+	}
+}
+
+func TestVerify_Repair(t *testing.T) {
+	old, new := storagemem.NewInMemoryStore(), storagemem.NewInMemoryStore()
+	ctx := pkg.WithTenant(context.Background(), "acme")
+	for range 4 {
+		create(t, old, ctx, "t")
+	}
+	if err := (&Copier{From: old, To: new}).Run(ctx); err != nil {
+		t.Fatalf("Run() err = %v", err)
+	}
+
+	// missing
+	if err := new.Remove(ctx, 1); err != nil {
+		t.Fatalf("Remove() err = %v", err)
+	}
+	// different
+	changed, _ := new.Get(ctx, 2)
+	changed.Title = "changed"
+	if err := new.Import(ctx, todo.Record{Tenant: "acme", Todo: &changed}); err != nil {
+		t.Fatalf("Import() err = %v", err)
+	}
+	// extra, and a counter the target is missing
+	create(t, new, pkg.WithTenant(context.Background(), "globex"), "t")
+	create(t, old, pkg.WithTenant(context.Background(), "initech"), "t")
+	if err := old.Remove(pkg.WithTenant(context.Background(), "initech"), 1); err != nil {
+		t.Fatalf("Remove() err = %v", err)
+	}
+
+	rep, err := Verify(context.Background(), old, new)
+	if err != nil {
+		t.Fatalf("Verify() err = %v", err)
+	}
+	kinds := map[DivergenceKind]int{}
+	for _, d := range rep.Divergences {
+		kinds[d.Kind]++
+	}
+	want := map[DivergenceKind]int{Missing: 1, Different: 1, Extra: 1, CounterBehind: 1}
+	if rep.Divergent != 4 || len(kinds) != len(want) {
+		t.Fatalf("Verify() divergences = %+v, want one of each kind", rep.Divergences)
+	}
+	for k, n := range want {
+		if kinds[k] != n {
+			t.Fatalf("Verify() %s = %d, want %d", k, kinds[k], n)
+		}
+	}
+
+	if err := Repair(context.Background(), new, rep.Divergences); err != nil {
+		t.Fatalf("Repair() err = %v", err)
+	}
+	verifyClean(t, old, new)
+}
+
+func TestVerifyRepair_BeyondReported(t *testing.T) {
+	old, new := storagemem.NewInMemoryStore(), storagemem.NewInMemoryStore()
+	ctx := pkg.WithTenant(context.Background(), "acme")
+	for range MaxReported + 50 {
+		create(t, old, ctx, "t")
+	}
+
+	rep, err := VerifyRepair(context.Background(), old, new)
+	if err != nil {
+		t.Fatalf("VerifyRepair() err = %v", err)
+	}
+	// every todo is missing and the counter behind
+	if rep.Divergent != MaxReported+51 || len(rep.Divergences) != MaxReported {
+		t.Fatalf("VerifyRepair() divergent = %d, listed = %d", rep.Divergent, len(rep.Divergences))
+	}
+	verifyClean(t, old, new)
+}
@@ -0,0 +1,224 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// MaxReported bounds the divergences listed in a Report, all are counted.
+const MaxReported = 100
+
+// repairBatch bounds the divergences VerifyRepair holds for one round.
+const repairBatch = 10_000
+
+// ErrDiverged is returned by VerifyRepair when repair makes no progress,
+// e.g. because writes keep failing to reach the target.
+var ErrDiverged = errors.New("backends still diverge after repair")
+
+type DivergenceKind string
+
+const (
+	// Missing todos are in the source only.
+	Missing DivergenceKind = "missing"
+	// Extra todos are in the target only.
+	Extra DivergenceKind = "extra"
+	// Different todos are in both with different fields.
+	Different DivergenceKind = "different"
+	// CounterBehind means the target would hand out ids the source used.
+	CounterBehind DivergenceKind = "counter_behind"
+)
+
+type Divergence struct {
+	Kind   DivergenceKind `json:"kind"`
+	Tenant string         `json:"tenant"`
+	ID     int64          `json:"id,omitempty"`
+	Source *todo.Todo     `json:"source,omitempty"`
+	Target *todo.Todo     `json:"target,omitempty"`
+	// LastID is the source's counter for CounterBehind.
+	LastID int64 `json:"last_id,omitempty"`
+}
+
+type Report struct {
+	Checked     int64        `json:"checked"`
+	Divergent   int64        `json:"divergent"`
+	Divergences []Divergence `json:"divergences"`
+}
+
+func (r *Report) add(d Divergence, limit int) {
+	r.Divergent++
+	if len(r.Divergences) < limit {
+		r.Divergences = append(r.Divergences, d)
+	}
+}
+
+// Verify compares target against source. Both exports are read side by side,
+// which works because they list todos in the same order.
+func Verify(ctx context.Context, source, target todo.Exporter) (Report, error) {
+	return verify(ctx, source, target, MaxReported)
+}
+
+// VerifyRepair compares target against source and repairs target until they
+// match, which may take several rounds when there are more divergences than
+// one round holds. It returns the report of the first round.
+func VerifyRepair(ctx context.Context, source todo.Exporter, target Store) (Report, error) {
+	var first Report
+	var left int64
+	for round := 0; ; round++ {
+		rep, err := verify(ctx, source, target, repairBatch)
+		if err != nil {
+			return first, err
+		}
+		if round == 0 {
+			first = rep
+			first.Divergences = first.Divergences[:min(len(rep.Divergences), MaxReported)]
+		}
+		if rep.Divergent == 0 {
+			return first, nil
+		}
+		if round > 0 && rep.Divergent >= left {
+			return first, fmt.Errorf("%w: %d divergences left", ErrDiverged, rep.Divergent)
+		}
+		left = rep.Divergent
+		if err := Repair(ctx, target, rep.Divergences); err != nil {
+			return first, err
+		}
+	}
+}
+
+func verify(ctx context.Context, source, target todo.Exporter, limit int) (Report, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	src := stream(ctx, g, source)
+	dst := stream(ctx, g, target)
+
+	rep := Report{Divergences: []Divergence{}}
+	g.Go(func() error {
+		a, aok := src.next()
+		b, bok := dst.next()
+		for aok || bok {
+			switch c := compareKeys(a, b, aok, bok); {
+			case c < 0:
+				rep.add(Divergence{Kind: Missing, Tenant: a.Tenant, ID: a.Todo.ID, Source: a.Todo}, limit)
+				a, aok = src.next()
+			case c > 0:
+				rep.add(Divergence{Kind: Extra, Tenant: b.Tenant, ID: b.Todo.ID, Target: b.Todo}, limit)
+				b, bok = dst.next()
+			default:
+				if !sameTodo(*a.Todo, *b.Todo) {
+					rep.add(Divergence{Kind: Different, Tenant: a.Tenant, ID: a.Todo.ID, Source: a.Todo, Target: b.Todo}, limit)
+				}
+				a, aok = src.next()
+				b, bok = dst.next()
+			}
+			rep.Checked++
+		}
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		return Report{}, err
+	}
+
+	for tenant, lastID := range src.counters {
+		if dst.counters[tenant] < lastID {
+			rep.add(Divergence{Kind: CounterBehind, Tenant: tenant, LastID: lastID}, limit)
+		}
+	}
+	return rep, nil
+}
+
+// Repair makes target match the source the divergences were found against.
+func Repair(ctx context.Context, target Store, divs []Divergence) error {
+	for _, d := range divs {
+		var err error
+		switch d.Kind {
+		case Missing, Different:
+			err = target.Import(ctx, todo.Record{Tenant: d.Tenant, Todo: d.Source})
+		case Extra:
+			err = target.Remove(pkg.WithTenant(ctx, d.Tenant), d.ID)
+			if errors.Is(err, todo.ErrNotFound) {
+				err = nil
+			}
+		case CounterBehind:
+			err = target.Import(ctx, todo.Record{Tenant: d.Tenant, LastID: d.LastID})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportStream hands out the todos of an export one by one and collects the
+// counters on the way.
+type exportStream struct {
+	ch       chan todo.Record
+	counters map[string]int64
+}
+
+func stream(ctx context.Context, g *errgroup.Group, src todo.Exporter) *exportStream {
+	s := &exportStream{ch: make(chan todo.Record, 64), counters: make(map[string]int64)}
+	g.Go(func() error {
+		defer close(s.ch)
+		return src.Export(ctx, func(r todo.Record) error {
+			select {
+			case s.ch <- r:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	})
+	return s
+}
+
+// next returns the next todo record, false at the end of the export.
+func (s *exportStream) next() (todo.Record, bool) {
+	for r := range s.ch {
+		if r.Todo == nil {
+			s.counters[r.Tenant] = r.LastID
+			continue
+		}
+		return r, true
+	}
+	return todo.Record{}, false
+}
+
+// compareKeys orders two todo records by tenant and id, a finished stream
+// sorts after everything.
+func compareKeys(a, b todo.Record, aok, bok bool) int {
+	switch {
+	case !bok:
+		return -1
+	case !aok:
+		return 1
+	}
+	if c := strings.Compare(a.Tenant, b.Tenant); c != 0 {
+		return c
+	}
+	switch {
+	case a.Todo.ID < b.Todo.ID:
+		return -1
+	case a.Todo.ID > b.Todo.ID:
+		return 1
+	}
+	return 0
+}
+
+// sameTodo compares todos up to the microsecond precision of Postgres.
+func sameTodo(a, b todo.Todo) bool {
+	if a.PublicID != b.PublicID || a.Title != b.Title || a.Status != b.Status {
+		return false
+	}
+	if (a.Description == nil) != (b.Description == nil) ||
+		a.Description != nil && *a.Description != *b.Description {
+		return false
+	}
+	return a.CreatedAt.Truncate(time.Microsecond).Equal(b.CreatedAt.Truncate(time.Microsecond)) &&
+		a.UpdatedAt.Truncate(time.Microsecond).Equal(b.UpdatedAt.Truncate(time.Microsecond))
+}
@@ -5,7 +5,8 @@ import "time"
 const StatusPending = "pending"
 
 type Todo struct {
-	ID          int64
+	ID          int64  // internal, never shown to clients
+	PublicID    string // identifies the todo in the API, see NewPublicID
 	Title       string
 	Description *string
 	Status      string
@@ -14,17 +15,19 @@ type Todo struct {
 }
 
 type TodoDTO struct {
-	ID          int64   `json:"id"`
+	ID          string  `json:"id"`
 	Title       string  `json:"title"`
 	Description *string `json:"description,omitempty"`
 	Status      string  `json:"status"`
 	CreatedAt   string  `json:"created_at"`
 	UpdatedAt   string  `json:"updated_at"`
+	// Links are URLs of related resources by relation, the handler adds them.
+	Links map[string]string `json:"links,omitempty"`
 }
 
 func ToDTO(t Todo) TodoDTO {
 	return TodoDTO{
-		ID:          t.ID,
+		ID:          t.PublicID,
 		Title:       t.Title,
 		Description: t.Description,
 		Status:      t.Status,
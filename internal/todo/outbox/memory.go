@@ -0,0 +1,105 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory is an in-process outbox.
+// It is meant to be appended to while the owner of the data holds its own lock,
+// so a message becomes visible together with the mutation it describes.
+type Memory struct {
+	mu      sync.Mutex
+	lastID  int64
+	pending []Message
+	claimed map[todoKey]bool // todos being processed right now
+}
+
+// todoKey identifies a todo, ids are only unique within a tenant.
+type todoKey struct {
+	tenant string
+	id     int64
+}
+
+func keyOf(m Message) todoKey {
+	return todoKey{m.Tenant, m.TodoID}
+}
+
+var _ Store = (*Memory)(nil)
+
+func NewMemory() *Memory {
+	return &Memory{claimed: make(map[todoKey]bool)}
+}
+
+func (m *Memory) Append(msgs ...Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, msg := range msgs {
+		m.lastID++
+		msg.ID = m.lastID
+		m.pending = append(m.pending, msg)
+	}
+}
+
+// Len returns the number of undelivered messages.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.pending)
+}
+
+// Process implements Store.
+func (m *Memory) Process(ctx context.Context, limit int, fn func(ctx context.Context, msgs []Message) []int64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	var batch []Message
+	seen := make(map[todoKey]bool)
+	for _, msg := range m.pending {
+		if len(batch) == limit {
+			break
+		}
+		k := keyOf(msg)
+		if seen[k] || m.claimed[k] {
+			seen[k] = true
+			continue
+		}
+		seen[k] = true
+		m.claimed[k] = true
+		batch = append(batch, msg)
+	}
+	m.mu.Unlock()
+
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	done := fn(ctx, batch)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, msg := range batch {
+		delete(m.claimed, keyOf(msg))
+	}
+	delivered := make(map[int64]bool, len(done))
+	for _, id := range done {
+		delivered[id] = true
+	}
+	n := 0
+	rest := m.pending[:0]
+	for _, msg := range m.pending {
+		if delivered[msg.ID] {
+			n++
+			continue
+		}
+		rest = append(rest, msg)
+	}
+	m.pending = rest
+
+	return n, nil
+}
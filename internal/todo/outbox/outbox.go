@@ -0,0 +1,58 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Message is a domain event stored in the outbox until it is published.
+type Message struct {
+	ID        int64
+	Tenant    string
+	TodoID    int64
+	Type      string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+func NewMessage(tenant string, todoID int64, typ string, payload any) (Message, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Tenant:    tenant,
+		TodoID:    todoID,
+		Type:      typ,
+		Payload:   b,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, m Message) error
+}
+
+type PublisherFunc func(ctx context.Context, m Message) error
+
+func (f PublisherFunc) Publish(ctx context.Context, m Message) error {
+	return f(ctx, m)
+}
+
+// LogPublisher writes every message to the standard logger.
+var LogPublisher = PublisherFunc(func(ctx context.Context, m Message) error {
+	log.Printf("outbox: %s tenant=%s todo=%d id=%d payload=%s", m.Type, m.Tenant, m.TodoID, m.ID, m.Payload)
+	return nil
+})
+
+// Store is the reading side of an outbox.
+//
+// Process claims up to limit undelivered messages, at most one per todo and
+// always the oldest pending one, so that messages of a single todo are never
+// handed out of order or concurrently. fn returns the IDs of the messages it
+// has delivered, those are marked and will not be claimed again.
+type Store interface {
+	Process(ctx context.Context, limit int, fn func(ctx context.Context, msgs []Message) []int64) (int, error)
+}
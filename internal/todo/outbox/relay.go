@@ -0,0 +1,75 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	defaultBatchSize = 100
+	defaultInterval  = time.Second
+)
+
+// Relay moves messages from a Store to a Publisher.
+// Delivery is at-least-once: a message is marked only after it was published.
+type Relay struct {
+	store     Store
+	pub       Publisher
+	interval  time.Duration
+	batchSize int
+}
+
+func NewRelay(store Store, pub Publisher, interval time.Duration, batchSize int) *Relay {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	return &Relay{
+		store:     store,
+		pub:       pub,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// RunOnce publishes one batch and returns the number of delivered messages.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	return r.store.Process(ctx, r.batchSize, func(ctx context.Context, msgs []Message) []int64 {
+		done := make([]int64, 0, len(msgs))
+		for _, m := range msgs {
+			if err := r.pub.Publish(ctx, m); err != nil {
+				// the message stays pending and blocks later ones of the same todo
+				log.Printf("outbox: publish %d failed: %s", m.ID, err)
+				continue
+			}
+			done = append(done, m.ID)
+		}
+		return done
+	})
+}
+
+// Run polls the store until ctx is cancelled.
+// A full batch is followed by the next one immediately.
+func (r *Relay) Run(ctx context.Context) error {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+
+	for {
+		n, err := r.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("outbox: relay error: %s", err)
+		}
+		if err == nil && n == r.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestRelay_PublishesAndMarks(t *testing.T) {
+	ob := NewMemory()
+	ob.Append(Message{TodoID: 1, Type: "a"}, Message{TodoID: 2, Type: "b"})
+
+	var got []Message
+	r := NewRelay(ob, PublisherFunc(func(ctx context.Context, m Message) error {
+		got = append(got, m)
+		return nil
+	}), 0, 10)
+
+	n, err := r.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	if n != 2 || len(got) != 2 {
+		t.Fatalf("delivered %d, published %d; want 2, 2", n, len(got))
+	}
+	if ob.Len() != 0 {
+		t.Fatalf("pending = %d, want 0", ob.Len())
+	}
+}
+
+func TestRelay_OrderPerTodo(t *testing.T) {
+	ob := NewMemory()
+	ob.Append(
+		Message{TodoID: 1, Type: "first"},
+		Message{TodoID: 1, Type: "second"},
+		Message{TodoID: 2, Type: "other"},
+	)
+
+	var got []string
+	r := NewRelay(ob, PublisherFunc(func(ctx context.Context, m Message) error {
+		if m.TodoID == 1 {
+			got = append(got, m.Type)
+		}
+		return nil
+	}), 0, 10)
+
+	// only the head of every todo is handed out per batch
+	n, _ := r.RunOnce(context.Background())
+	if n != 2 {
+		t.Fatalf("first batch delivered %d, want 2", n)
+	}
+	n, _ = r.RunOnce(context.Background())
+	if n != 1 {
+		t.Fatalf("second batch delivered %d, want 1", n)
+	}
+
+	if len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Fatalf("order = %v, want [first second]", got)
+	}
+}
+
+func TestRelay_FailedPublishIsRetried(t *testing.T) {
+	ob := NewMemory()
+	ob.Append(Message{TodoID: 1, Type: "a"}, Message{TodoID: 1, Type: "b"})
+
+	fail := true
+	var got []string
+	r := NewRelay(ob, PublisherFunc(func(ctx context.Context, m Message) error {
+		if fail {
+			return errors.New("broker down")
+		}
+		got = append(got, m.Type)
+		return nil
+	}), 0, 10)
+
+	if n, _ := r.RunOnce(context.Background()); n != 0 {
+		t.Fatalf("delivered %d, want 0", n)
+	}
+	if ob.Len() != 2 {
+		t.Fatalf("pending = %d, want 2", ob.Len())
+	}
+
+	fail = false
+	r.RunOnce(context.Background())
+	r.RunOnce(context.Background())
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("published %v, want [a b]", got)
+	}
+}
+
+func TestMemory_ConcurrentRelaysKeepOrder(t *testing.T) {
+	ob := NewMemory()
+	const perTodo = 50
+	for i := 0; i < perTodo; i++ {
+		ob.Append(Message{TodoID: 1, Payload: []byte{byte(i)}}, Message{TodoID: 2, Payload: []byte{byte(i)}})
+	}
+
+	var mu sync.Mutex
+	last := map[int64]int{1: -1, 2: -1}
+	pub := PublisherFunc(func(ctx context.Context, m Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if int(m.Payload[0]) != last[m.TodoID]+1 {
+			t.Errorf("todo %d: got %d after %d", m.TodoID, m.Payload[0], last[m.TodoID])
+		}
+		last[m.TodoID] = int(m.Payload[0])
+		return nil
+	})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := NewRelay(ob, pub, 0, 1)
+			for ob.Len() > 0 {
+				r.RunOnce(context.Background())
+			}
+		}()
+	}
+	wg.Wait()
+
+	if last[1] != perTodo-1 || last[2] != perTodo-1 {
+		t.Fatalf("last delivered = %v, want %d each", last, perTodo-1)
+	}
+}
@@ -0,0 +1,67 @@
+package todo
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// NewPublicID returns a UUIDv7: the creation time in milliseconds, then the
+// sub-millisecond fraction and random bits. IDs sort by creation time and are
+// safe to show, they don't reveal how many todos exist.
+func NewPublicID() string {
+	return newPublicID(time.Now())
+}
+
+func newPublicID(now time.Time) string {
+	var u [16]byte
+	_, _ = rand.Read(u[6:])
+
+	ms := now.UnixMilli()
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+
+	// 12 bits of the sub-millisecond fraction keep IDs of the same millisecond ordered
+	frac := (now.UnixNano() % int64(time.Millisecond)) * 4096 / int64(time.Millisecond)
+	u[6] = 0x70 | byte(frac>>8)
+	u[7] = byte(frac)
+	u[8] = 0x80 | u[8]&0x3f // RFC 9562 variant
+
+	var b [36]byte
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
+
+// ValidPublicID reports whether s looks like an ID made by NewPublicID,
+// in lower case canonical form.
+func ValidPublicID(s string) bool {
+	if len(s) != 36 || s[14] != '7' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package todo
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestNewPublicID(t *testing.T) {
+	seen := make(map[string]bool)
+	ids := make([]string, 0, 1000)
+	now := time.Now()
+	for i := 0; i < 1000; i++ {
+		id := newPublicID(now.Add(time.Duration(i) * 100 * time.Microsecond))
+		if !ValidPublicID(id) {
+			t.Fatalf("NewPublicID() = %q, not valid", id)
+		}
+		if seen[id] {
+			t.Fatalf("NewPublicID() = %q twice", id)
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Fatalf("NewPublicID() ids do not sort by creation time")
+	}
+}
+
+func TestValidPublicID(t *testing.T) {
+	for id, want := range map[string]bool{
+		"01890a5d-ac96-774b-bcce-b302099a8057": true,
+		"01890A5D-AC96-774B-BCCE-B302099A8057": false,
+		"01890a5d-ac96-474b-bcce-b302099a8057": false, // version 4
+		"01890a5dac96774bbcceb302099a8057":     false,
+		"123":                                  false,
+		"":                                     false,
+	} {
+		if got := ValidPublicID(id); got != want {
+			t.Errorf("ValidPublicID(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
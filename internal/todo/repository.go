@@ -5,6 +5,9 @@ import "context"
 type Repository interface {
 	Create(ctx context.Context, t Todo) (Todo, error)
 	Get(ctx context.Context, id int64) (Todo, error)
+	// Resolve returns the id of the todo with the given public id,
+	// ErrNotFound if there is none.
+	Resolve(ctx context.Context, publicID string) (int64, error)
 	Remove(ctx context.Context, id int64) error
 	Ping(ctx context.Context) error
 }
@@ -0,0 +1,453 @@
+// Package repotest is the behavioral contract every todo.Repository
+// implementation has to satisfy.
+//
+//	func TestContract(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) todo.Repository {
+//			return NewInMemoryStore()
+//		})
+//	}
+package repotest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+)
+
+// Factory returns a new, empty repository. It is called once per subtest and
+// should register its cleanup with t.Cleanup.
+type Factory func(t *testing.T) todo.Repository
+
+// Run runs the whole contract against repositories built by newRepo.
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, r todo.Repository)
+	}{
+		{"Ping", testPing},
+		{"CreateAssignsFields", testCreateAssignsFields},
+		{"CreateStatus", testCreateStatus},
+		{"CreateUniqueIDs", testCreateUniqueIDs},
+		{"GetReturnsCreated", testGetReturnsCreated},
+		{"GetNotFound", testGetNotFound},
+		{"RemoveDeletes", testRemoveDeletes},
+		{"RemoveNotFound", testRemoveNotFound},
+		{"RemoveKeepsOthers", testRemoveKeepsOthers},
+		{"IDsNotReused", testIDsNotReused},
+		{"ConcurrentCreate", testConcurrentCreate},
+		{"ConcurrentRemove", testConcurrentRemove},
+		{"ConcurrentReadWrite", testConcurrentReadWrite},
+		{"CancelledContext", testCancelledContext},
+		{"PublicIDs", testPublicIDs},
+		{"TenantIsolation", testTenantIsolation},
+		{"TenantSequences", testTenantSequences},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newRepo(t))
+		})
+	}
+}
+
+func mustCreate(t *testing.T, r todo.Repository, in todo.Todo) todo.Todo {
+	t.Helper()
+
+	out, err := r.Create(context.Background(), in)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return out
+}
+
+func testPing(t *testing.T, r todo.Repository) {
+	if err := r.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+}
+
+func testCreateAssignsFields(t *testing.T, r todo.Repository) {
+	before := time.Now().UTC().Add(-time.Second)
+	desc := "description"
+	out := mustCreate(t, r, todo.Todo{ID: 1000, Title: "title", Description: &desc})
+	after := time.Now().UTC().Add(time.Second)
+
+	if out.ID <= 0 {
+		t.Fatalf("Create() id = %d, want positive", out.ID)
+	}
+	if out.Title != "title" || out.Description == nil || *out.Description != desc {
+		t.Fatalf("Create() = %v, want input title and description", out)
+	}
+	if out.CreatedAt.Location() != time.UTC || out.UpdatedAt.Location() != time.UTC {
+		t.Fatalf("Create() timestamps not in UTC: %v, %v", out.CreatedAt, out.UpdatedAt)
+	}
+	if out.CreatedAt.Before(before) || out.CreatedAt.After(after) {
+		t.Fatalf("Create() created_at = %v, want between %v and %v", out.CreatedAt, before, after)
+	}
+	if !out.UpdatedAt.Equal(out.CreatedAt) {
+		t.Fatalf("Create() updated_at = %v, want created_at %v", out.UpdatedAt, out.CreatedAt)
+	}
+
+	noDesc := mustCreate(t, r, todo.Todo{Title: "no description"})
+	if noDesc.Description != nil {
+		t.Fatalf("Create() description = %q, want nil", *noDesc.Description)
+	}
+}
+
+func testCreateStatus(t *testing.T, r todo.Repository) {
+	for in, want := range map[string]string{
+		"":       todo.StatusPending,
+		"   ":    todo.StatusPending,
+		"Done":   "done",
+		"ACTIVE": "active",
+	} {
+		out := mustCreate(t, r, todo.Todo{Title: "t", Status: in})
+		if out.Status != want {
+			t.Fatalf("Create() status %q = %q, want %q", in, out.Status, want)
+		}
+		got, err := r.Get(context.Background(), out.ID)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if got.Status != want {
+			t.Fatalf("Get() status %q = %q, want %q", in, got.Status, want)
+		}
+	}
+}
+
+func testCreateUniqueIDs(t *testing.T, r todo.Repository) {
+	seen := make(map[int64]bool)
+	for i := 0; i < 10; i++ {
+		out := mustCreate(t, r, todo.Todo{Title: "t"})
+		if seen[out.ID] {
+			t.Fatalf("Create() id %d returned twice", out.ID)
+		}
+		seen[out.ID] = true
+	}
+}
+
+func testGetReturnsCreated(t *testing.T, r todo.Repository) {
+	desc := "d"
+	out := mustCreate(t, r, todo.Todo{Title: "t", Description: &desc})
+
+	got, err := r.Get(context.Background(), out.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.ID != out.ID || got.Title != out.Title || got.Status != out.Status {
+		t.Fatalf("Get() = %v, want %v", got, out)
+	}
+	if got.Description == nil || *got.Description != desc {
+		t.Fatalf("Get() description = %v, want %q", got.Description, desc)
+	}
+	// backends may store timestamps with less precision than time.Time
+	if d := got.CreatedAt.Sub(out.CreatedAt).Abs(); d > time.Millisecond {
+		t.Fatalf("Get() created_at = %v, want %v", got.CreatedAt, out.CreatedAt)
+	}
+	if d := got.UpdatedAt.Sub(out.UpdatedAt).Abs(); d > time.Millisecond {
+		t.Fatalf("Get() updated_at = %v, want %v", got.UpdatedAt, out.UpdatedAt)
+	}
+}
+
+func testGetNotFound(t *testing.T, r todo.Repository) {
+	out := mustCreate(t, r, todo.Todo{Title: "t"})
+
+	for _, id := range []int64{out.ID + 1, 0, -1} {
+		got, err := r.Get(context.Background(), id)
+		if !errors.Is(err, todo.ErrNotFound) {
+			t.Fatalf("Get(%d) err = %v, want %v", id, err, todo.ErrNotFound)
+		}
+		if got != (todo.Todo{}) {
+			t.Fatalf("Get(%d) = %v, want zero value", id, got)
+		}
+	}
+}
+
+func testRemoveDeletes(t *testing.T, r todo.Repository) {
+	out := mustCreate(t, r, todo.Todo{Title: "t"})
+
+	if err := r.Remove(context.Background(), out.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := r.Get(context.Background(), out.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Get() after Remove() err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if err := r.Remove(context.Background(), out.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("second Remove() err = %v, want %v", err, todo.ErrNotFound)
+	}
+}
+
+func testRemoveNotFound(t *testing.T, r todo.Repository) {
+	out := mustCreate(t, r, todo.Todo{Title: "t"})
+
+	for _, id := range []int64{out.ID + 1, 0, -1} {
+		if err := r.Remove(context.Background(), id); !errors.Is(err, todo.ErrNotFound) {
+			t.Fatalf("Remove(%d) err = %v, want %v", id, err, todo.ErrNotFound)
+		}
+	}
+}
+
+func testRemoveKeepsOthers(t *testing.T, r todo.Repository) {
+	a := mustCreate(t, r, todo.Todo{Title: "a"})
+	b := mustCreate(t, r, todo.Todo{Title: "b"})
+
+	if err := r.Remove(context.Background(), a.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	got, err := r.Get(context.Background(), b.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Title != "b" {
+		t.Fatalf("Get() title = %q, want b", got.Title)
+	}
+}
+
+func testIDsNotReused(t *testing.T, r todo.Repository) {
+	a := mustCreate(t, r, todo.Todo{Title: "a"})
+	if err := r.Remove(context.Background(), a.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+
+	b := mustCreate(t, r, todo.Todo{Title: "b"})
+	if b.ID <= a.ID {
+		t.Fatalf("Create() id = %d after removing %d, want greater", b.ID, a.ID)
+	}
+}
+
+func testConcurrentCreate(t *testing.T, r todo.Repository) {
+	const n = 50
+
+	ids := make(chan int64, n)
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out, err := r.Create(context.Background(), todo.Todo{Title: "t"})
+			if err != nil {
+				t.Errorf("Create() error = %v", err)
+				return
+			}
+			ids <- out.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int64]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("Create() id %d returned twice", id)
+		}
+		seen[id] = true
+		if _, err := r.Get(context.Background(), id); err != nil {
+			t.Fatalf("Get(%d) error = %v", id, err)
+		}
+	}
+	if len(seen) != n {
+		t.Fatalf("created %d todos, want %d", len(seen), n)
+	}
+}
+
+func testConcurrentRemove(t *testing.T, r todo.Repository) {
+	const n = 20
+	out := mustCreate(t, r, todo.Todo{Title: "t"})
+
+	var mu sync.Mutex
+	removed := 0
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := r.Remove(context.Background(), out.ID)
+			if err != nil && !errors.Is(err, todo.ErrNotFound) {
+				t.Errorf("Remove() error = %v", err)
+				return
+			}
+			if err == nil {
+				mu.Lock()
+				removed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if removed != 1 {
+		t.Fatalf("Remove() succeeded %d times, want exactly once", removed)
+	}
+}
+
+func testConcurrentReadWrite(t *testing.T, r todo.Repository) {
+	const n = 20
+	out := mustCreate(t, r, todo.Todo{Title: "stable"})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c, err := r.Create(context.Background(), todo.Todo{Title: "t"})
+			if err != nil {
+				t.Errorf("Create() error = %v", err)
+				return
+			}
+			if err := r.Remove(context.Background(), c.ID); err != nil {
+				t.Errorf("Remove() error = %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			got, err := r.Get(context.Background(), out.ID)
+			if err != nil {
+				t.Errorf("Get() error = %v", err)
+				return
+			}
+			if got.Title != "stable" {
+				t.Errorf("Get() title = %q, want stable", got.Title)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func testCancelledContext(t *testing.T, r todo.Repository) {
+	out := mustCreate(t, r, todo.Todo{Title: "t"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := r.Create(ctx, todo.Todo{Title: "t"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Create() err = %v, want %v", err, context.Canceled)
+	}
+	if _, err := r.Get(ctx, out.ID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Get() err = %v, want %v", err, context.Canceled)
+	}
+	if _, err := r.Resolve(ctx, out.PublicID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Resolve() err = %v, want %v", err, context.Canceled)
+	}
+	if err := r.Remove(ctx, out.ID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Remove() err = %v, want %v", err, context.Canceled)
+	}
+	if err := r.Ping(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Ping() err = %v, want %v", err, context.Canceled)
+	}
+
+	// nothing was changed by the cancelled calls
+	if _, err := r.Get(context.Background(), out.ID); err != nil {
+		t.Fatalf("Get() after cancelled Remove() error = %v", err)
+	}
+}
+
+func testPublicIDs(t *testing.T, r todo.Repository) {
+	a := mustCreate(t, r, todo.Todo{Title: "a"})
+	b := mustCreate(t, r, todo.Todo{Title: "b"})
+
+	if !todo.ValidPublicID(a.PublicID) || a.PublicID == b.PublicID {
+		t.Fatalf("Create() public ids = %q, %q, want distinct UUIDv7", a.PublicID, b.PublicID)
+	}
+	got, err := r.Get(context.Background(), a.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.PublicID != a.PublicID {
+		t.Fatalf("Get() public id = %q, want %q", got.PublicID, a.PublicID)
+	}
+
+	id, err := r.Resolve(context.Background(), a.PublicID)
+	if err != nil || id != a.ID {
+		t.Fatalf("Resolve() = %d, %v; want %d", id, err, a.ID)
+	}
+	for _, pid := range []string{todo.NewPublicID(), "", "not-an-id"} {
+		if _, err := r.Resolve(context.Background(), pid); !errors.Is(err, todo.ErrNotFound) {
+			t.Fatalf("Resolve(%q) err = %v, want %v", pid, err, todo.ErrNotFound)
+		}
+	}
+	other := pkg.WithTenant(context.Background(), "tenant-a")
+	if _, err := r.Resolve(other, a.PublicID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Resolve() from other tenant err = %v, want %v", err, todo.ErrNotFound)
+	}
+
+	if err := r.Remove(context.Background(), a.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := r.Resolve(context.Background(), a.PublicID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Resolve() after Remove() err = %v, want %v", err, todo.ErrNotFound)
+	}
+
+	// a public id given by the caller is kept, e.g. when copying data
+	pid := todo.NewPublicID()
+	c := mustCreate(t, r, todo.Todo{PublicID: pid, Title: "c"})
+	if c.PublicID != pid {
+		t.Fatalf("Create() public id = %q, want %q", c.PublicID, pid)
+	}
+}
+
+func testTenantIsolation(t *testing.T, r todo.Repository) {
+	ctxA := pkg.WithTenant(context.Background(), "tenant-a")
+	ctxB := pkg.WithTenant(context.Background(), "tenant-b")
+
+	a, err := r.Create(ctxA, todo.Todo{Title: "a"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	for name, ctx := range map[string]context.Context{"other": ctxB, "default": context.Background()} {
+		if _, err := r.Get(ctx, a.ID); !errors.Is(err, todo.ErrNotFound) {
+			t.Fatalf("Get() from %s tenant err = %v, want %v", name, err, todo.ErrNotFound)
+		}
+		if err := r.Remove(ctx, a.ID); !errors.Is(err, todo.ErrNotFound) {
+			t.Fatalf("Remove() from %s tenant err = %v, want %v", name, err, todo.ErrNotFound)
+		}
+	}
+
+	got, err := r.Get(ctxA, a.ID)
+	if err != nil {
+		t.Fatalf("Get() from own tenant error = %v", err)
+	}
+	if got.Title != "a" {
+		t.Fatalf("Get() title = %q, want a", got.Title)
+	}
+
+	// the same id in another tenant is another todo
+	b, err := r.Create(ctxB, todo.Todo{Title: "b"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if b.ID != a.ID {
+		t.Fatalf("Create() id = %d in a new tenant, want %d", b.ID, a.ID)
+	}
+	if err := r.Remove(ctxB, b.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := r.Get(ctxA, a.ID); err != nil {
+		t.Fatalf("Get() after other tenant's Remove() error = %v", err)
+	}
+}
+
+func testTenantSequences(t *testing.T, r todo.Repository) {
+	ctxA := pkg.WithTenant(context.Background(), "tenant-a")
+	ctxB := pkg.WithTenant(context.Background(), "tenant-b")
+
+	for i := 0; i < 5; i++ {
+		mustCreate(t, r, todo.Todo{Title: "busy"})
+		if _, err := r.Create(ctxA, todo.Todo{Title: "busy"}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	// ids do not reveal how many todos other tenants have
+	b, err := r.Create(ctxB, todo.Todo{Title: "b"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if b.ID != 1 {
+		t.Fatalf("Create() first id of a tenant = %d, want 1", b.ID)
+	}
+}
@@ -0,0 +1,208 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"todo-api/internal/todo"
+)
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// OpenError is returned without calling the storage while the breaker is open.
+// It matches todo.ErrUnavailable.
+type OpenError struct {
+	retryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open, retry after %s", e.retryAfter)
+}
+
+func (e *OpenError) Is(target error) bool {
+	return target == todo.ErrUnavailable
+}
+
+func (e *OpenError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+type BreakerOptions struct {
+	// FailureThreshold consecutive transient failures open the breaker.
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before probing.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of calls let through while half-open.
+	HalfOpenProbes int
+}
+
+// Breaker is a circuit breaker in front of a todo.Repository.
+// Only transient errors, see IsTransient, count as failures.
+type Breaker struct {
+	next todo.Repository
+	opts BreakerOptions
+	now  func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probes   int // in flight while half-open
+}
+
+var _ todo.Repository = (*Breaker)(nil)
+
+func NewBreaker(next todo.Repository, opts BreakerOptions) *Breaker {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = 10 * time.Second
+	}
+	if opts.HalfOpenProbes <= 0 {
+		opts.HalfOpenProbes = 1
+	}
+	return &Breaker{next: next, opts: opts, now: time.Now}
+}
+
+// State returns the current state, an open breaker whose timeout has passed
+// is reported as half-open.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && !b.now().Before(b.openedAt.Add(b.opts.OpenTimeout)) {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// acquire decides whether a call may go through.
+func (b *Breaker) acquire() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		wait := b.openedAt.Add(b.opts.OpenTimeout).Sub(b.now())
+		if wait > 0 {
+			return &OpenError{retryAfter: wait}
+		}
+		b.state = StateHalfOpen
+		b.probes = 0
+		fallthrough
+	case StateHalfOpen:
+		if b.probes >= b.opts.HalfOpenProbes {
+			return &OpenError{retryAfter: b.opts.OpenTimeout}
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// release records the outcome of a call let through by acquire.
+func (b *Breaker) release(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := IsTransient(err)
+	if errors.Is(err, context.Canceled) {
+		// the caller gave up, the outcome says nothing about the storage
+		if b.state == StateHalfOpen {
+			b.probes--
+		}
+		return
+	}
+
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.opts.FailureThreshold {
+			b.trip()
+		}
+	case StateHalfOpen:
+		b.probes--
+		if failed {
+			b.trip()
+			return
+		}
+		b.state = StateClosed
+		b.failures = 0
+	}
+}
+
+func (b *Breaker) trip() {
+	b.state = StateOpen
+	b.openedAt = b.now()
+	b.failures = 0
+	b.probes = 0
+}
+
+func (b *Breaker) Create(ctx context.Context, t todo.Todo) (todo.Todo, error) {
+	if err := b.acquire(); err != nil {
+		return todo.Todo{}, err
+	}
+	out, err := b.next.Create(ctx, t)
+	b.release(err)
+	return out, err
+}
+
+func (b *Breaker) Get(ctx context.Context, id int64) (todo.Todo, error) {
+	if err := b.acquire(); err != nil {
+		return todo.Todo{}, err
+	}
+	out, err := b.next.Get(ctx, id)
+	b.release(err)
+	return out, err
+}
+
+func (b *Breaker) Resolve(ctx context.Context, publicID string) (int64, error) {
+	if err := b.acquire(); err != nil {
+		return 0, err
+	}
+	id, err := b.next.Resolve(ctx, publicID)
+	b.release(err)
+	return id, err
+}
+
+func (b *Breaker) Remove(ctx context.Context, id int64) error {
+	if err := b.acquire(); err != nil {
+		return err
+	}
+	err := b.next.Remove(ctx, id)
+	b.release(err)
+	return err
+}
+
+func (b *Breaker) Ping(ctx context.Context) error {
+	if err := b.acquire(); err != nil {
+		return err
+	}
+	err := b.next.Ping(ctx)
+	b.release(err)
+	return err
+}
@@ -0,0 +1,74 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"todo-api/internal/todo"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// IsTransient reports whether err is a failure of the storage itself that may
+// go away, as opposed to a domain error like todo.ErrNotFound.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, todo.ErrNotFound) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var st interface{ SQLState() string }
+	if errors.As(err, &st) {
+		return transientSQLState(st.SQLState())
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	var ce *pgconn.ConnectError
+	return errors.As(err, &ce)
+}
+
+func transientSQLState(code string) bool {
+	switch code {
+	case "40001", // serialization_failure
+		"40P01", // deadlock_detected
+		"53300", // too_many_connections
+		"57P01", // admin_shutdown
+		"57P02", // crash_shutdown
+		"57P03": // cannot_connect_now
+		return true
+	}
+	// class 08: connection exception
+	return len(code) == 5 && code[:2] == "08"
+}
+
+// NotExecuted reports whether err guarantees that a statement had no effect,
+// so a write can be repeated without being applied twice.
+func NotExecuted(err error) bool {
+	if err == nil {
+		return false
+	}
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	var ce *pgconn.ConnectError
+	if errors.As(err, &ce) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var st interface{ SQLState() string }
+	if errors.As(err, &st) {
+		switch st.SQLState() {
+		case "40001", "40P01", "53300", "57P03", "08001", "08004":
+			return true
+		}
+	}
+	return false
+}
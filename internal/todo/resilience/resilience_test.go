@@ -0,0 +1,275 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"todo-api/internal/todo"
+	"todo-api/internal/todo/repotest"
+	"todo-api/internal/todo/storagemem"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// flakyRepo fails the next fail calls with err, then delegates.
+type flakyRepo struct {
+	todo.Repository
+	err   error
+	fail  atomic.Int64
+	calls atomic.Int64
+}
+
+func (r *flakyRepo) result() error {
+	r.calls.Add(1)
+	if r.fail.Add(-1) >= 0 {
+		return r.err
+	}
+	return nil
+}
+
+func (r *flakyRepo) Create(ctx context.Context, t todo.Todo) (todo.Todo, error) {
+	if err := r.result(); err != nil {
+		return todo.Todo{}, err
+	}
+	return r.Repository.Create(ctx, t)
+}
+
+func (r *flakyRepo) Get(ctx context.Context, id int64) (todo.Todo, error) {
+	if err := r.result(); err != nil {
+		return todo.Todo{}, err
+	}
+	return r.Repository.Get(ctx, id)
+}
+
+func (r *flakyRepo) Ping(ctx context.Context) error {
+	if err := r.result(); err != nil {
+		return err
+	}
+	return r.Repository.Ping(ctx)
+}
+
+func newFlaky(err error, fail int64) *flakyRepo {
+	r := &flakyRepo{Repository: storagemem.NewInMemoryStore(), err: err}
+	r.fail.Store(fail)
+	return r
+}
+
+var (
+	errConnLost = &pgconn.PgError{Code: "08006"}
+	errSerial   = &pgconn.PgError{Code: "40001"}
+)
+
+func TestContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) todo.Repository {
+		var repo todo.Repository = storagemem.NewInMemoryStore()
+		repo = WithTimeouts(repo, Timeouts{Read: time.Second, Write: time.Second})
+		repo = WithRetry(repo, RetryOptions{BaseDelay: time.Millisecond})
+		return NewBreaker(repo, BreakerOptions{})
+	})
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{todo.ErrNotFound, false},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, true},
+		{errConnLost, true},
+		{errSerial, true},
+		{&pgconn.PgError{Code: "23505"}, false},
+		{fmt.Errorf("wrapped: %w", errConnLost), true},
+		{errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetry_ReadRetriesTransient(t *testing.T) {
+	next := newFlaky(errConnLost, 2)
+	created, _ := next.Repository.Create(context.Background(), todo.Todo{Title: "a"})
+	repo := WithRetry(next, RetryOptions{Attempts: 3, BaseDelay: time.Millisecond})
+
+	got, err := repo.Get(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("Get() err = %v, want nil", err)
+	}
+	if got.ID != created.ID {
+		t.Fatalf("Get() id = %d, want %d", got.ID, created.ID)
+	}
+	if n := next.calls.Load(); n != 3 {
+		t.Fatalf("calls = %d, want 3", n)
+	}
+}
+
+func TestRetry_GivesUp(t *testing.T) {
+	next := newFlaky(errConnLost, 10)
+	repo := WithRetry(next, RetryOptions{Attempts: 3, BaseDelay: time.Millisecond})
+
+	if _, err := repo.Get(context.Background(), 1); !errors.Is(err, errConnLost) {
+		t.Fatalf("Get() err = %v, want %v", err, errConnLost)
+	}
+	if n := next.calls.Load(); n != 3 {
+		t.Fatalf("calls = %d, want 3", n)
+	}
+}
+
+func TestRetry_WriteOnlyWhenNotExecuted(t *testing.T) {
+	// A dropped connection may have committed the insert, retrying would duplicate it.
+	next := newFlaky(errConnLost, 1)
+	repo := WithRetry(next, RetryOptions{Attempts: 3, BaseDelay: time.Millisecond})
+	if _, err := repo.Create(context.Background(), todo.Todo{Title: "a"}); err == nil {
+		t.Fatal("Create() err = nil, want connection error")
+	}
+	if n := next.calls.Load(); n != 1 {
+		t.Fatalf("calls = %d, want 1", n)
+	}
+
+	next = newFlaky(errSerial, 1)
+	repo = WithRetry(next, RetryOptions{Attempts: 3, BaseDelay: time.Millisecond})
+	if _, err := repo.Create(context.Background(), todo.Todo{Title: "a"}); err != nil {
+		t.Fatalf("Create() err = %v, want nil", err)
+	}
+	if n := next.calls.Load(); n != 2 {
+		t.Fatalf("calls = %d, want 2", n)
+	}
+}
+
+func TestRetry_NotFoundNotRetried(t *testing.T) {
+	next := newFlaky(nil, 0)
+	repo := WithRetry(next, RetryOptions{Attempts: 3, BaseDelay: time.Millisecond})
+
+	if _, err := repo.Get(context.Background(), 42); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Get() err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if n := next.calls.Load(); n != 1 {
+		t.Fatalf("calls = %d, want 1", n)
+	}
+}
+
+type slowRepo struct {
+	todo.Repository
+}
+
+func (r slowRepo) Get(ctx context.Context, id int64) (todo.Todo, error) {
+	<-ctx.Done()
+	return todo.Todo{}, ctx.Err()
+}
+
+func TestTimeouts_BoundRead(t *testing.T) {
+	repo := WithTimeouts(slowRepo{storagemem.NewInMemoryStore()}, Timeouts{Read: 10 * time.Millisecond})
+
+	start := time.Now()
+	if _, err := repo.Get(context.Background(), 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get() err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Get() took %s, want ~10ms", d)
+	}
+}
+
+func newTestBreaker(next todo.Repository) (*Breaker, *time.Time) {
+	now := time.Unix(1000, 0)
+	b := NewBreaker(next, BreakerOptions{FailureThreshold: 3, OpenTimeout: 5 * time.Second})
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	next := newFlaky(errConnLost, 100)
+	b, _ := newTestBreaker(next)
+
+	for i := 0; i < 3; i++ {
+		if err := b.Ping(context.Background()); !errors.Is(err, errConnLost) {
+			t.Fatalf("Ping() #%d err = %v, want %v", i, err, errConnLost)
+		}
+	}
+	if s := b.State(); s != StateOpen {
+		t.Fatalf("State() = %s, want open", s)
+	}
+
+	err := b.Ping(context.Background())
+	if !errors.Is(err, todo.ErrUnavailable) {
+		t.Fatalf("Ping() err = %v, want %v", err, todo.ErrUnavailable)
+	}
+	var ra todo.RetryAfterError
+	if !errors.As(err, &ra) || ra.RetryAfter() != 5*time.Second {
+		t.Fatalf("Ping() err = %v, want RetryAfter 5s", err)
+	}
+	if n := next.calls.Load(); n != 3 {
+		t.Fatalf("calls = %d, want 3", n)
+	}
+}
+
+func TestBreaker_DomainErrorsDoNotTrip(t *testing.T) {
+	b, _ := newTestBreaker(storagemem.NewInMemoryStore())
+
+	for i := 0; i < 10; i++ {
+		if _, err := b.Get(context.Background(), 99); !errors.Is(err, todo.ErrNotFound) {
+			t.Fatalf("Get() err = %v, want %v", err, todo.ErrNotFound)
+		}
+	}
+	if s := b.State(); s != StateClosed {
+		t.Fatalf("State() = %s, want closed", s)
+	}
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	next := newFlaky(errConnLost, 4)
+	b, now := newTestBreaker(next)
+	for i := 0; i < 3; i++ {
+		_ = b.Ping(context.Background())
+	}
+
+	// failed probe opens it again
+	*now = now.Add(5 * time.Second)
+	if s := b.State(); s != StateHalfOpen {
+		t.Fatalf("State() = %s, want half-open", s)
+	}
+	if err := b.Ping(context.Background()); !errors.Is(err, errConnLost) {
+		t.Fatalf("Ping() err = %v, want %v", err, errConnLost)
+	}
+	if s := b.State(); s != StateOpen {
+		t.Fatalf("State() = %s, want open", s)
+	}
+
+	// successful probe closes it
+	*now = now.Add(5 * time.Second)
+	if err := b.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() err = %v, want nil", err)
+	}
+	if s := b.State(); s != StateClosed {
+		t.Fatalf("State() = %s, want closed", s)
+	}
+}
+
+func TestHandler_BreakerOpen_503(t *testing.T) {
+	b, _ := newTestBreaker(newFlaky(errConnLost, 100))
+	for i := 0; i < 3; i++ {
+		_ = b.Ping(context.Background())
+	}
+	h := todo.NewHandler(b)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/todos", strings.NewReader(`{"title":"Buy milk"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	h.Create(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status=%d, want %d; body=%s", rr.Code, http.StatusServiceUnavailable, rr.Body.String())
+	}
+	if ra := rr.Header().Get("Retry-After"); ra != "5" {
+		t.Fatalf("Retry-After=%q, want 5", ra)
+	}
+}
@@ -0,0 +1,89 @@
+package resilience
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+	"todo-api/internal/todo"
+)
+
+type RetryOptions struct {
+	// Attempts is the total number of tries, including the first one.
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+type retryRepo struct {
+	next todo.Repository
+	opts RetryOptions
+}
+
+// WithRetry returns next retrying failed calls with exponential backoff and
+// jitter. Reads are retried on transient errors, writes only on errors that
+// guarantee they had no effect.
+func WithRetry(next todo.Repository, opts RetryOptions) todo.Repository {
+	if opts.Attempts <= 0 {
+		opts.Attempts = 3
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = 50 * time.Millisecond
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = time.Second
+	}
+	return &retryRepo{next: next, opts: opts}
+}
+
+func (r *retryRepo) do(ctx context.Context, retryable func(error) bool, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.opts.Attempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		delay := min(r.opts.BaseDelay<<(attempt-1), r.opts.MaxDelay)
+		delay = delay/2 + rand.N(delay/2+1)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (r *retryRepo) Create(ctx context.Context, t todo.Todo) (out todo.Todo, err error) {
+	err = r.do(ctx, NotExecuted, func() error {
+		out, err = r.next.Create(ctx, t)
+		return err
+	})
+	return out, err
+}
+
+func (r *retryRepo) Get(ctx context.Context, id int64) (out todo.Todo, err error) {
+	err = r.do(ctx, IsTransient, func() error {
+		out, err = r.next.Get(ctx, id)
+		return err
+	})
+	return out, err
+}
+
+func (r *retryRepo) Resolve(ctx context.Context, publicID string) (id int64, err error) {
+	err = r.do(ctx, IsTransient, func() error {
+		id, err = r.next.Resolve(ctx, publicID)
+		return err
+	})
+	return id, err
+}
+
+func (r *retryRepo) Remove(ctx context.Context, id int64) error {
+	return r.do(ctx, NotExecuted, func() error {
+		return r.next.Remove(ctx, id)
+	})
+}
+
+func (r *retryRepo) Ping(ctx context.Context) error {
+	return r.do(ctx, IsTransient, func() error {
+		return r.next.Ping(ctx)
+	})
+}
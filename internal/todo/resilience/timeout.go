@@ -0,0 +1,61 @@
+package resilience
+
+import (
+	"context"
+	"time"
+	"todo-api/internal/todo"
+)
+
+// Timeouts bounds every call of a kind, 0 means no bound.
+type Timeouts struct {
+	Read  time.Duration // Get, Resolve
+	Write time.Duration // Create, Remove
+	Ping  time.Duration
+}
+
+type timeoutRepo struct {
+	next todo.Repository
+	t    Timeouts
+}
+
+// WithTimeouts returns next with per-operation timeouts.
+func WithTimeouts(next todo.Repository, t Timeouts) todo.Repository {
+	return &timeoutRepo{next: next, t: t}
+}
+
+func bound(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+func (r *timeoutRepo) Create(ctx context.Context, t todo.Todo) (todo.Todo, error) {
+	ctx, cancel := bound(ctx, r.t.Write)
+	defer cancel()
+	return r.next.Create(ctx, t)
+}
+
+func (r *timeoutRepo) Get(ctx context.Context, id int64) (todo.Todo, error) {
+	ctx, cancel := bound(ctx, r.t.Read)
+	defer cancel()
+	return r.next.Get(ctx, id)
+}
+
+func (r *timeoutRepo) Resolve(ctx context.Context, publicID string) (int64, error) {
+	ctx, cancel := bound(ctx, r.t.Read)
+	defer cancel()
+	return r.next.Resolve(ctx, publicID)
+}
+
+func (r *timeoutRepo) Remove(ctx context.Context, id int64) error {
+	ctx, cancel := bound(ctx, r.t.Write)
+	defer cancel()
+	return r.next.Remove(ctx, id)
+}
+
+func (r *timeoutRepo) Ping(ctx context.Context) error {
+	ctx, cancel := bound(ctx, r.t.Ping)
+	defer cancel()
+	return r.next.Ping(ctx)
+}
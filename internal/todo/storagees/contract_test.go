@@ -0,0 +1,13 @@
+package storagees
+
+import (
+	"testing"
+	"todo-api/internal/todo"
+	"todo-api/internal/todo/repotest"
+)
+
+func TestContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) todo.Repository {
+		return open(t, t.TempDir(), 0)
+	})
+}
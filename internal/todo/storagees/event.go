@@ -0,0 +1,189 @@
+package storagees
+
+import (
+	"time"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+)
+
+const (
+	TodoCreated   = "TodoCreated"
+	TodoRenamed   = "TodoRenamed"
+	StatusChanged = "StatusChanged"
+	TodoRemoved   = "TodoRemoved"
+	// PublicIDAssigned gives a public id to a todo created before they existed.
+	PublicIDAssigned = "PublicIDAssigned"
+	// TodoRestored puts a todo from a backup, replacing one with the same id.
+	TodoRestored = "TodoRestored"
+	// CounterAdvanced moves a tenant's id counter forward to LastID.
+	CounterAdvanced = "CounterAdvanced"
+)
+
+// Event is one entry of the append-only log.
+// Only the fields relevant for its Type are set.
+type Event struct {
+	Seq         int64      `json:"seq"`
+	Type        string     `json:"type"`
+	Tenant      string     `json:"tenant,omitempty"` // empty before tenants existed
+	TodoID      int64      `json:"todo_id"`
+	PublicID    string     `json:"public_id,omitempty"`
+	At          time.Time  `json:"at"`
+	Title       string     `json:"title,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	Todo        *todo.Todo `json:"todo,omitempty"`
+	LastID      int64      `json:"last_id,omitempty"`
+}
+
+// state is the current view folded from the events.
+type state struct {
+	Seq     int64                   `json:"seq"`
+	Tenants map[string]*tenantState `json:"tenants"`
+	// LastID and Items hold the default tenant's data in snapshots taken
+	// before tenants existed.
+	LastID int64               `json:"last_id,omitempty"`
+	Items  map[int64]todo.Todo `json:"items,omitempty"`
+}
+
+type tenantState struct {
+	LastID int64               `json:"last_id"`
+	Items  map[int64]todo.Todo `json:"items"`
+	public map[string]int64    // public id to id, rebuilt on load
+}
+
+func newState() state {
+	return state{Tenants: make(map[string]*tenantState)}
+}
+
+// tenant returns the state of id, creating it when needed.
+func (s *state) tenant(id string) *tenantState {
+	if id == "" {
+		id = pkg.DefaultTenant
+	}
+	ts, ok := s.Tenants[id]
+	if !ok {
+		ts = &tenantState{Items: make(map[int64]todo.Todo), public: make(map[string]int64)}
+		s.Tenants[id] = ts
+	}
+	return ts
+}
+
+// get returns the todo id of tenant.
+func (s *state) get(tenant string, id int64) (todo.Todo, bool) {
+	ts, ok := s.Tenants[tenant]
+	if !ok {
+		return todo.Todo{}, false
+	}
+	t, ok := ts.Items[id]
+	return t, ok
+}
+
+// resolve returns the id of the todo of tenant with the given public id.
+func (s *state) resolve(tenant, publicID string) (int64, bool) {
+	ts, ok := s.Tenants[tenant]
+	if !ok {
+		return 0, false
+	}
+	id, ok := ts.public[publicID]
+	return id, ok
+}
+
+// len returns the number of todos of all tenants.
+func (s *state) len() int {
+	n := 0
+	for _, ts := range s.Tenants {
+		n += len(ts.Items)
+	}
+	return n
+}
+
+// upgrade moves the data of an old snapshot to the default tenant and
+// rebuilds the public id indexes.
+func (s *state) upgrade() {
+	if s.Tenants == nil {
+		s.Tenants = make(map[string]*tenantState)
+	}
+	if s.LastID != 0 || len(s.Items) != 0 {
+		ts := s.tenant(pkg.DefaultTenant)
+		for id, t := range s.Items {
+			ts.Items[id] = t
+		}
+		ts.LastID = max(ts.LastID, s.LastID)
+		s.LastID, s.Items = 0, nil
+	}
+
+	for _, ts := range s.Tenants {
+		if ts.Items == nil {
+			ts.Items = make(map[int64]todo.Todo)
+		}
+		ts.public = make(map[string]int64, len(ts.Items))
+		for id, t := range ts.Items {
+			if t.PublicID != "" {
+				ts.public[t.PublicID] = id
+			}
+		}
+	}
+}
+
+// apply folds e into s. Events for unknown todos are ignored.
+func (s *state) apply(e Event) {
+	s.Seq = e.Seq
+	ts := s.tenant(e.Tenant)
+
+	switch e.Type {
+	case TodoCreated:
+		ts.Items[e.TodoID] = todo.Todo{
+			ID:          e.TodoID,
+			PublicID:    e.PublicID,
+			Title:       e.Title,
+			Description: e.Description,
+			Status:      e.Status,
+			CreatedAt:   e.At,
+			UpdatedAt:   e.At,
+		}
+		if e.TodoID > ts.LastID {
+			ts.LastID = e.TodoID
+		}
+		if e.PublicID != "" {
+			ts.public[e.PublicID] = e.TodoID
+		}
+	case TodoRenamed:
+		if t, ok := ts.Items[e.TodoID]; ok {
+			t.Title = e.Title
+			t.UpdatedAt = e.At
+			ts.Items[e.TodoID] = t
+		}
+	case StatusChanged:
+		if t, ok := ts.Items[e.TodoID]; ok {
+			t.Status = e.Status
+			t.UpdatedAt = e.At
+			ts.Items[e.TodoID] = t
+		}
+	case PublicIDAssigned:
+		if t, ok := ts.Items[e.TodoID]; ok {
+			delete(ts.public, t.PublicID)
+			t.PublicID = e.PublicID
+			ts.Items[e.TodoID] = t
+			ts.public[e.PublicID] = e.TodoID
+		}
+	case TodoRestored:
+		if e.Todo == nil {
+			return
+		}
+		if old, ok := ts.Items[e.Todo.ID]; ok {
+			delete(ts.public, old.PublicID)
+		}
+		ts.Items[e.Todo.ID] = *e.Todo
+		ts.LastID = max(ts.LastID, e.Todo.ID)
+		if e.Todo.PublicID != "" {
+			ts.public[e.Todo.PublicID] = e.Todo.ID
+		}
+	case CounterAdvanced:
+		ts.LastID = max(ts.LastID, e.LastID)
+	case TodoRemoved:
+		if t, ok := ts.Items[e.TodoID]; ok {
+			delete(ts.public, t.PublicID)
+			delete(ts.Items, e.TodoID)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package storagees
+
+import (
+	"context"
+	"slices"
+	"todo-api/internal/todo"
+)
+
+var (
+	_ todo.Exporter = (*EventStore)(nil)
+	_ todo.Importer = (*EventStore)(nil)
+)
+
+// Export implements todo.Exporter. The records are copied under the read
+// lock, fn runs without it and does not hold up writers.
+func (s *EventStore) Export(ctx context.Context, fn func(todo.Record) error) error {
+	for _, r := range s.records() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// records returns every record of the store in export order.
+func (s *EventStore) records() []todo.Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenants := make([]string, 0, len(s.state.Tenants))
+	for tenant := range s.state.Tenants {
+		tenants = append(tenants, tenant)
+	}
+	slices.Sort(tenants)
+
+	var out []todo.Record
+	for _, tenant := range tenants {
+		ts := s.state.Tenants[tenant]
+		out = append(out, todo.Record{Tenant: tenant, LastID: ts.LastID})
+
+		ids := make([]int64, 0, len(ts.Items))
+		for id := range ts.Items {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		for _, id := range ids {
+			t := ts.Items[id]
+			out = append(out, todo.Record{Tenant: tenant, Todo: &t})
+		}
+	}
+	return out
+}
+
+// Import implements todo.Importer.
+func (s *EventStore) Import(ctx context.Context, r todo.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Todo == nil {
+		if r.LastID <= s.state.tenant(r.Tenant).LastID {
+			return nil
+		}
+		return s.append(Event{Type: CounterAdvanced, Tenant: r.Tenant, LastID: r.LastID})
+	}
+	t := *r.Todo
+	return s.append(Event{Type: TodoRestored, Tenant: r.Tenant, TodoID: t.ID, Todo: &t})
+}
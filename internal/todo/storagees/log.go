@@ -0,0 +1,80 @@
+package storagees
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"todo-api/internal/pkg"
+)
+
+const (
+	logFile      = "events.log"
+	snapshotFile = "snapshot.json"
+)
+
+// snapshot is the folded state together with the log offset it covers.
+type snapshot struct {
+	State  state `json:"state"`
+	Offset int64 `json:"offset"`
+}
+
+// readLog folds every event stored after offset into s and returns the offset
+// of the end of the last complete event. A torn event at the tail, left by a
+// crash in the middle of a write, is not an error.
+func readLog(f *os.File, offset int64, s *state) (int64, error) {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return offset, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		e := Event{}
+		if err := json.Unmarshal(bytes.TrimSpace(line), &e); err != nil {
+			return 0, fmt.Errorf("event log at offset %d: %w", offset, err)
+		}
+		if e.Seq <= s.Seq {
+			return 0, fmt.Errorf("event log at offset %d: sequence %d after %d", offset, e.Seq, s.Seq)
+		}
+		s.apply(e)
+		offset += int64(len(line))
+	}
+}
+
+func readSnapshot(dir string) (snapshot, error) {
+	snap := snapshot{State: newState()}
+
+	b, err := os.ReadFile(filepath.Join(dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return snap, nil
+	}
+	if err != nil {
+		return snapshot{}, err
+	}
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return snapshot{}, fmt.Errorf("snapshot: %w", err)
+	}
+	snap.State.upgrade()
+	return snap, nil
+}
+
+// writeSnapshot replaces the snapshot atomically.
+func writeSnapshot(dir string, snap snapshot) error {
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	return pkg.WriteFileAtomic(filepath.Join(dir, snapshotFile), b)
+}
@@ -0,0 +1,299 @@
+package storagees
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+)
+
+const DefaultSnapshotEvery = 1000
+
+// EventStore keeps todos as an append-only event log in a directory.
+// The current state lives in memory and is rebuilt on Open by folding the log,
+// starting from the latest snapshot.
+type EventStore struct {
+	mu            sync.RWMutex
+	dir           string
+	log           *os.File
+	lock          *pkg.DirLock
+	offset        int64
+	state         state
+	snapshotEvery int
+	sinceSnapshot int
+}
+
+var _ todo.Repository = (*EventStore)(nil)
+
+// Open loads the store from dir, creating it when needed.
+// A snapshot is taken every snapshotEvery events. The directory stays locked
+// until Close, opening it while another store has it fails with pkg.ErrLocked.
+func Open(dir string, snapshotEvery int) (*EventStore, error) {
+	if snapshotEvery <= 0 {
+		snapshotEvery = DefaultSnapshotEvery
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	lock, err := pkg.LockDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dir, err)
+	}
+	s, err := openDir(dir, snapshotEvery)
+	if err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+	s.lock = lock
+	return s, nil
+}
+
+func openDir(dir string, snapshotEvery int) (*EventStore, error) {
+	f, err := os.OpenFile(filepath.Join(dir, logFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &EventStore{
+		dir:           dir,
+		log:           f,
+		snapshotEvery: snapshotEvery,
+	}
+
+	snap, err := readSnapshot(dir)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := s.load(snap); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := s.backfillPublicIDs(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load folds the log on top of snap and positions the log for appending.
+// Whatever follows the last complete event is cut off.
+func (s *EventStore) load(snap snapshot) error {
+	st := snap.State
+	offset, err := readLog(s.log, snap.Offset, &st)
+	if err != nil {
+		return err
+	}
+	if err := s.log.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := s.log.Seek(offset, 0); err != nil {
+		return err
+	}
+
+	s.state = st
+	s.offset = offset
+	return nil
+}
+
+// backfillPublicIDs records a public id for todos created before they existed.
+func (s *EventStore) backfillPublicIDs() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for tenant, ts := range s.state.Tenants {
+		for id, t := range ts.Items {
+			if t.PublicID != "" {
+				continue
+			}
+			err := s.append(Event{Type: PublicIDAssigned, Tenant: tenant, TodoID: id, PublicID: todo.NewPublicID()})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Rebuild discards the in-memory state and replays the whole log,
+// ignoring the snapshot.
+func (s *EventStore) Rebuild() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load(snapshot{State: newState()})
+}
+
+func (s *EventStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.log.Close()
+	if uerr := s.lock.Unlock(); err == nil {
+		err = uerr
+	}
+	return err
+}
+
+// Ping implements todo.Repository.
+func (s *EventStore) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Create implements todo.Repository.
+func (s *EventStore) Create(ctx context.Context, t todo.Todo) (todo.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return todo.Todo{}, err
+	}
+
+	tenant := pkg.TenantFrom(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := todo.StatusPending
+	if strings.TrimSpace(t.Status) != "" {
+		status = strings.ToLower(t.Status)
+	}
+	id := s.state.tenant(tenant).LastID + 1
+	if t.PublicID == "" {
+		t.PublicID = todo.NewPublicID()
+	}
+
+	err := s.append(Event{
+		Type:        TodoCreated,
+		Tenant:      tenant,
+		TodoID:      id,
+		PublicID:    t.PublicID,
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      status,
+	})
+	if err != nil {
+		return todo.Todo{}, err
+	}
+	t, _ = s.state.get(tenant, id)
+	return t, nil
+}
+
+// Get implements todo.Repository.
+func (s *EventStore) Get(ctx context.Context, id int64) (todo.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return todo.Todo{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.state.get(pkg.TenantFrom(ctx), id)
+	if !ok {
+		return todo.Todo{}, todo.ErrNotFound
+	}
+	return t, nil
+}
+
+// Resolve implements todo.Repository.
+func (s *EventStore) Resolve(ctx context.Context, publicID string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.state.resolve(pkg.TenantFrom(ctx), publicID)
+	if !ok {
+		return 0, todo.ErrNotFound
+	}
+	return id, nil
+}
+
+// Remove implements todo.Repository.
+func (s *EventStore) Remove(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tenant := pkg.TenantFrom(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.state.get(tenant, id); !ok {
+		return todo.ErrNotFound
+	}
+	return s.append(Event{Type: TodoRemoved, Tenant: tenant, TodoID: id})
+}
+
+func (s *EventStore) Rename(ctx context.Context, id int64, title string) (todo.Todo, error) {
+	tenant := pkg.TenantFrom(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.state.get(tenant, id); !ok {
+		return todo.Todo{}, todo.ErrNotFound
+	}
+	if err := s.append(Event{Type: TodoRenamed, Tenant: tenant, TodoID: id, Title: title}); err != nil {
+		return todo.Todo{}, err
+	}
+	t, _ := s.state.get(tenant, id)
+	return t, nil
+}
+
+func (s *EventStore) SetStatus(ctx context.Context, id int64, status string) (todo.Todo, error) {
+	tenant := pkg.TenantFrom(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.state.get(tenant, id); !ok {
+		return todo.Todo{}, todo.ErrNotFound
+	}
+	err := s.append(Event{Type: StatusChanged, Tenant: tenant, TodoID: id, Status: strings.ToLower(status)})
+	if err != nil {
+		return todo.Todo{}, err
+	}
+	t, _ := s.state.get(tenant, id)
+	return t, nil
+}
+
+// append durably writes e to the log and applies it. The caller must hold s.mu.
+func (s *EventStore) append(e Event) error {
+	e.Seq = s.state.Seq + 1
+	e.At = time.Now().UTC()
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if err := s.write(b); err != nil {
+		// drop a partially written event so the next one starts clean
+		_ = s.log.Truncate(s.offset)
+		_, _ = s.log.Seek(s.offset, 0)
+		return err
+	}
+
+	s.offset += int64(len(b))
+	s.state.apply(e)
+
+	s.sinceSnapshot++
+	if s.sinceSnapshot >= s.snapshotEvery {
+		// a failed snapshot only slows down the next start
+		if err := writeSnapshot(s.dir, snapshot{State: s.state, Offset: s.offset}); err == nil {
+			s.sinceSnapshot = 0
+		}
+	}
+	return nil
+}
+
+func (s *EventStore) write(b []byte) error {
+	if _, err := s.log.Write(b); err != nil {
+		return err
+	}
+	return s.log.Sync()
+}
@@ -0,0 +1,228 @@
+package storagees
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+)
+
+func open(t *testing.T, dir string, every int) *EventStore {
+	t.Helper()
+
+	s, err := Open(dir, every)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestCreateGetRemove(t *testing.T) {
+	s := open(t, t.TempDir(), 0)
+	ctx := context.Background()
+
+	out, err := s.Create(ctx, todo.Todo{Title: "title", Status: "  "})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if out.ID != 1 || out.Status != todo.StatusPending || out.CreatedAt.IsZero() {
+		t.Fatalf("Create() unexpected todo: %v", out)
+	}
+
+	got, err := s.Get(ctx, out.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got != out {
+		t.Fatalf("Get() = %v, want %v", got, out)
+	}
+
+	if err := s.Remove(ctx, out.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := s.Get(ctx, out.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Get() err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if err := s.Remove(ctx, out.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Remove() err = %v, want %v", err, todo.ErrNotFound)
+	}
+}
+
+func TestReopen_ReplaysLog(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	a, _ := s.Create(ctx, todo.Todo{Title: "a"})
+	b, _ := s.Create(ctx, todo.Todo{Title: "b"})
+	if _, err := s.Rename(ctx, a.ID, "renamed"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if _, err := s.SetStatus(ctx, a.ID, "DONE"); err != nil {
+		t.Fatalf("SetStatus() error = %v", err)
+	}
+	s.Remove(ctx, b.ID)
+	s.Close()
+
+	s = open(t, dir, 0)
+	got, err := s.Get(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Title != "renamed" || got.Status != "done" {
+		t.Fatalf("Get() = %v, want renamed/done", got)
+	}
+	if _, err := s.Get(ctx, b.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Get() removed err = %v, want %v", err, todo.ErrNotFound)
+	}
+
+	// ids are never reused
+	c, _ := s.Create(ctx, todo.Todo{Title: "c"})
+	if c.ID != 3 {
+		t.Fatalf("Create() id = %d, want 3", c.ID)
+	}
+}
+
+func TestOpen_Locked(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := Open(dir, 0); !errors.Is(err, pkg.ErrLocked) {
+		t.Fatalf("Open() err = %v, want %v", err, pkg.ErrLocked)
+	}
+	s.Close()
+
+	open(t, dir, 0)
+}
+
+func TestSnapshot_UsedOnOpen(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	s, _ := Open(dir, 2)
+	for i := 0; i < 5; i++ {
+		s.Create(ctx, todo.Todo{Title: "t"})
+	}
+	s.Close()
+
+	snap, err := readSnapshot(dir)
+	if err != nil {
+		t.Fatalf("readSnapshot() error = %v", err)
+	}
+	if snap.State.Seq != 4 || snap.State.len() != 4 {
+		t.Fatalf("snapshot seq=%d items=%d, want 4 and 4", snap.State.Seq, snap.State.len())
+	}
+
+	s = open(t, dir, 2)
+	if s.state.Seq != 5 || s.state.len() != 5 {
+		t.Fatalf("state seq=%d items=%d, want 5 and 5", s.state.Seq, s.state.len())
+	}
+
+	if err := s.Rebuild(); err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	if s.state.Seq != 5 || s.state.len() != 5 {
+		t.Fatalf("rebuilt seq=%d items=%d, want 5 and 5", s.state.Seq, s.state.len())
+	}
+}
+
+func TestOpen_TornTail(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	s, _ := Open(dir, 0)
+	s.Create(ctx, todo.Todo{Title: "kept"})
+	s.Close()
+
+	f, _ := os.OpenFile(filepath.Join(dir, logFile), os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"seq":2,"type":"TodoCrea`)
+	f.Close()
+
+	s = open(t, dir, 0)
+	if s.state.len() != 1 {
+		t.Fatalf("items = %d, want 1", s.state.len())
+	}
+	out, err := s.Create(ctx, todo.Todo{Title: "next"})
+	if err != nil || out.ID != 2 {
+		t.Fatalf("Create() = %v, %v; want id 2", out, err)
+	}
+}
+
+func TestOpen_BackfillsPublicIDs(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `{"seq":1,"type":"TodoCreated","todo_id":1,"at":"2024-01-01T00:00:00Z","title":"old","status":"pending"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, logFile), []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	old, err := s.Get(context.Background(), 1)
+	if err != nil || !todo.ValidPublicID(old.PublicID) {
+		t.Fatalf("Get() = %v, %v; want a backfilled public id", old, err)
+	}
+	s.Close()
+
+	s = open(t, dir, 0)
+	if id, err := s.Resolve(context.Background(), old.PublicID); err != nil || id != 1 {
+		t.Fatalf("Resolve() after reopen = %d, %v; want 1", id, err)
+	}
+	if s.state.Seq != 2 {
+		t.Fatalf("seq = %d, want 2 after a single backfill", s.state.Seq)
+	}
+}
+
+func TestImport_SurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, 0)
+	ctx := context.Background()
+
+	in := todo.Todo{
+		ID:        7,
+		PublicID:  todo.NewPublicID(),
+		Title:     "restored",
+		Status:    todo.StatusPending,
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC),
+	}
+	if err := s.Import(ctx, todo.Record{Tenant: "acme", LastID: 9}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if err := s.Import(ctx, todo.Record{Tenant: "acme", Todo: &in}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	s.Close()
+
+	s = open(t, dir, 0)
+	actx := pkg.WithTenant(ctx, "acme")
+	got, err := s.Get(actx, 7)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got != in {
+		t.Fatalf("Get() = %v, want %v", got, in)
+	}
+	if id, err := s.Resolve(actx, in.PublicID); err != nil || id != 7 {
+		t.Fatalf("Resolve() = %d, %v, want 7", id, err)
+	}
+	next, err := s.Create(actx, todo.Todo{Title: "next"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if next.ID != 10 {
+		t.Fatalf("Create() id = %d, want 10", next.ID)
+	}
+}
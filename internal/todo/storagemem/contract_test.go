@@ -0,0 +1,19 @@
+package storagemem
+
+import (
+	"testing"
+	"todo-api/internal/todo"
+	"todo-api/internal/todo/repotest"
+)
+
+func TestContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) todo.Repository {
+		return NewInMemoryStore()
+	})
+}
+
+func TestContract_File(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) todo.Repository {
+		return openFile(t, t.TempDir(), FileOptions{Sync: SyncNever})
+	})
+}
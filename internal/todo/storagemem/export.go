@@ -0,0 +1,83 @@
+package storagemem
+
+import (
+	"context"
+	"slices"
+	"todo-api/internal/todo"
+)
+
+var (
+	_ todo.Exporter = (*InMemoryStore)(nil)
+	_ todo.Importer = (*InMemoryStore)(nil)
+)
+
+// Export implements todo.Exporter. The records are copied under the read
+// lock, fn runs without it and does not hold up writers.
+func (s *InMemoryStore) Export(ctx context.Context, fn func(todo.Record) error) error {
+	for _, r := range s.records() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// records returns every record of the store in export order.
+func (s *InMemoryStore) records() []todo.Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenants := make([]string, 0, len(s.lastID))
+	for tenant := range s.lastID {
+		tenants = append(tenants, tenant)
+	}
+	slices.Sort(tenants)
+
+	var out []todo.Record
+	for _, tenant := range tenants {
+		out = append(out, todo.Record{Tenant: tenant, LastID: s.lastID[tenant]})
+
+		ids := make([]int64, 0, len(s.items[tenant]))
+		for id := range s.items[tenant] {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		for _, id := range ids {
+			t := s.items[tenant][id]
+			out = append(out, todo.Record{Tenant: tenant, Todo: &t})
+		}
+	}
+	return out
+}
+
+// Import implements todo.Importer. No outbox events are emitted.
+func (s *InMemoryStore) Import(ctx context.Context, r todo.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Todo == nil {
+		if r.LastID <= s.lastID[r.Tenant] {
+			return nil
+		}
+		if err := s.persist(record{Op: opSeq, Tenant: r.Tenant, ID: r.LastID}); err != nil {
+			return err
+		}
+		s.lastID[r.Tenant] = r.LastID
+		return nil
+	}
+
+	t := *r.Todo
+	if err := s.persist(record{Op: opPut, Tenant: r.Tenant, Todo: &t}); err != nil {
+		return err
+	}
+	s.del(r.Tenant, t.ID)
+	s.put(r.Tenant, t)
+	return nil
+}
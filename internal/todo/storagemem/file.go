@@ -0,0 +1,401 @@
+package storagemem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+)
+
+type SyncPolicy int
+
+const (
+	// SyncAlways fsyncs the WAL before a mutation is acknowledged.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval fsyncs in the background, a crash may lose the last interval.
+	SyncInterval
+	// SyncNever leaves flushing to the operating system.
+	SyncNever
+)
+
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "always":
+		return SyncAlways, nil
+	case "interval":
+		return SyncInterval, nil
+	case "never":
+		return SyncNever, nil
+	}
+	return SyncAlways, fmt.Errorf("unknown sync policy %q", s)
+}
+
+const (
+	snapshotName        = "snapshot.json"
+	defaultCompactEvery = 10000
+	defaultSyncInterval = time.Second
+)
+
+var ErrClosed = errors.New("store is closed")
+
+type FileOptions struct {
+	Sync         SyncPolicy
+	SyncInterval time.Duration
+	// CompactEvery is the number of WAL records after which the log is
+	// compacted into a snapshot.
+	CompactEvery int
+}
+
+// fileState is the persistence part of a store opened with OpenFileStore.
+// Its fields are guarded by the store's mu, compaction is serialized by compactMu.
+type fileState struct {
+	dir        string
+	opts       FileOptions
+	lock       *pkg.DirLock
+	wal        *os.File
+	gen        int64
+	size       int64
+	records    int
+	dirty      bool
+	compacting bool
+	compactMu  sync.Mutex
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+type snapshotFile struct {
+	// Gen is the first WAL generation not contained in the snapshot.
+	Gen     int64            `json:"gen"`
+	Tenants []tenantSnapshot `json:"tenants"`
+	// LastID and Items are the default tenant's data in snapshots written
+	// before tenants existed.
+	LastID int64       `json:"last_id,omitempty"`
+	Items  []todo.Todo `json:"items,omitempty"`
+}
+
+type tenantSnapshot struct {
+	Tenant string      `json:"tenant"`
+	LastID int64       `json:"last_id"`
+	Items  []todo.Todo `json:"items"`
+}
+
+// OpenFileStore returns an in-memory store persisted in dir.
+// Every mutation is appended to a write-ahead log which is compacted into a
+// snapshot from time to time. On open the snapshot is loaded and the log
+// replayed on top of it, a torn record at the end of the log is dropped and a
+// damaged one elsewhere fails with ErrCorruptWAL. The directory stays locked
+// until Close, opening it while another store has it fails with pkg.ErrLocked.
+func OpenFileStore(dir string, fo FileOptions, opts ...Option) (*InMemoryStore, error) {
+	if fo.CompactEvery <= 0 {
+		fo.CompactEvery = defaultCompactEvery
+	}
+	if fo.SyncInterval <= 0 {
+		fo.SyncInterval = defaultSyncInterval
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	lock, err := pkg.LockDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dir, err)
+	}
+	s, err := openFileStore(dir, fo, lock, opts...)
+	if err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+	return s, nil
+}
+
+func openFileStore(dir string, fo FileOptions, lock *pkg.DirLock, opts ...Option) (*InMemoryStore, error) {
+	s := NewInMemoryStore(opts...)
+	f := &fileState{dir: dir, opts: fo, lock: lock}
+
+	snap, err := readSnapshotFile(dir)
+	if err != nil {
+		return nil, err
+	}
+	if snap.LastID > 0 || len(snap.Items) > 0 {
+		snap.Tenants = append(snap.Tenants, tenantSnapshot{pkg.DefaultTenant, snap.LastID, snap.Items})
+	}
+	for _, ts := range snap.Tenants {
+		for _, t := range ts.Items {
+			s.put(ts.Tenant, t)
+		}
+		s.lastID[ts.Tenant] = max(s.lastID[ts.Tenant], ts.LastID)
+	}
+
+	gens, err := listWALs(dir)
+	if err != nil {
+		return nil, err
+	}
+	f.gen = snap.Gen
+	for i, gen := range gens {
+		if gen < snap.Gen {
+			// already in the snapshot, left over by an interrupted compaction
+			_ = os.Remove(walPath(dir, gen))
+			continue
+		}
+		n, size, err := replayWAL(walPath(dir, gen), i == len(gens)-1, s.apply)
+		if err != nil {
+			return nil, err
+		}
+		f.gen, f.size, f.records = gen, size, n
+	}
+
+	f.wal, err = os.OpenFile(walPath(dir, f.gen), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	s.file = f
+	if err := s.backfillPublicIDs(); err != nil {
+		f.wal.Close()
+		return nil, err
+	}
+	if fo.Sync == SyncInterval {
+		f.stop = make(chan struct{})
+		f.done = make(chan struct{})
+		go s.syncLoop()
+	}
+	return s, nil
+}
+
+// apply replays a WAL record. Replaying a record twice is harmless.
+func (s *InMemoryStore) apply(r record) {
+	tenant := r.Tenant
+	if tenant == "" {
+		tenant = pkg.DefaultTenant
+	}
+	switch r.Op {
+	case opPut:
+		if r.Todo == nil {
+			return
+		}
+		s.del(tenant, r.Todo.ID)
+		s.put(tenant, *r.Todo)
+	case opDelete:
+		s.del(tenant, r.ID)
+	case opSeq:
+		s.lastID[tenant] = max(s.lastID[tenant], r.ID)
+	}
+}
+
+// backfillPublicIDs gives a public id to todos written before they existed.
+func (s *InMemoryStore) backfillPublicIDs() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for tenant, items := range s.items {
+		for _, t := range items {
+			if t.PublicID != "" {
+				continue
+			}
+			t.PublicID = todo.NewPublicID()
+			if err := s.persist(record{Op: opPut, Tenant: tenant, Todo: &t}); err != nil {
+				return err
+			}
+			s.put(tenant, t)
+		}
+	}
+	return nil
+}
+
+// persist appends r to the WAL. The caller must hold s.mu.
+func (s *InMemoryStore) persist(r record) error {
+	f := s.file
+	if f == nil {
+		return nil
+	}
+	if f.wal == nil {
+		return ErrClosed
+	}
+
+	b, err := encodeRecord(r)
+	if err != nil {
+		return err
+	}
+	if _, err := f.wal.Write(b); err != nil {
+		_ = f.wal.Truncate(f.size)
+		return err
+	}
+	if f.opts.Sync == SyncAlways {
+		if err := f.wal.Sync(); err != nil {
+			_ = f.wal.Truncate(f.size)
+			return err
+		}
+	} else {
+		f.dirty = true
+	}
+	f.size += int64(len(b))
+	f.records++
+
+	if f.records >= f.opts.CompactEvery && !f.compacting {
+		f.compacting = true
+		go func() {
+			if err := s.Compact(); err != nil {
+				log.Printf("storagemem: compaction failed: %s", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// Compact writes the current state into a snapshot and removes the WAL files
+// it covers. It is a no-op for a store without persistence.
+func (s *InMemoryStore) Compact() error {
+	f := s.file
+	if f == nil {
+		return nil
+	}
+	f.compactMu.Lock()
+	defer f.compactMu.Unlock()
+
+	// switch to a new WAL so mutations can go on while the snapshot is written
+	s.mu.Lock()
+	if f.wal == nil {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	snap, err := s.rotate()
+	f.compacting = false
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := writeSnapshotFile(f.dir, snap); err != nil {
+		return err
+	}
+
+	gens, err := listWALs(f.dir)
+	if err != nil {
+		return err
+	}
+	for _, gen := range gens {
+		if gen < snap.Gen {
+			if err := os.Remove(walPath(f.dir, gen)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rotate starts the next WAL generation and returns a snapshot of everything
+// written before it. The caller must hold s.mu.
+func (s *InMemoryStore) rotate() (snapshotFile, error) {
+	f := s.file
+	if err := f.wal.Sync(); err != nil {
+		return snapshotFile{}, err
+	}
+
+	next, err := os.OpenFile(walPath(f.dir, f.gen+1), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return snapshotFile{}, err
+	}
+	_ = f.wal.Close()
+	f.wal = next
+	f.gen++
+	f.size, f.records, f.dirty = 0, 0, false
+
+	snap := snapshotFile{Gen: f.gen}
+	for tenant, lastID := range s.lastID {
+		ts := tenantSnapshot{Tenant: tenant, LastID: lastID, Items: make([]todo.Todo, 0, len(s.items[tenant]))}
+		for _, t := range s.items[tenant] {
+			ts.Items = append(ts.Items, t)
+		}
+		snap.Tenants = append(snap.Tenants, ts)
+	}
+	return snap, nil
+}
+
+func (s *InMemoryStore) syncLoop() {
+	f := s.file
+	defer close(f.done)
+
+	t := time.NewTicker(f.opts.SyncInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-t.C:
+		}
+
+		s.mu.Lock()
+		if f.dirty && f.wal != nil {
+			if err := f.wal.Sync(); err != nil {
+				log.Printf("storagemem: wal sync failed: %s", err)
+			} else {
+				f.dirty = false
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Close flushes and closes the WAL and unlocks the directory. It is a no-op
+// for a store without persistence.
+func (s *InMemoryStore) Close() error {
+	f := s.file
+	if f == nil {
+		return nil
+	}
+	if f.stop != nil {
+		close(f.stop)
+		<-f.done
+		f.stop = nil
+	}
+
+	f.compactMu.Lock()
+	defer f.compactMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f.wal == nil {
+		return nil
+	}
+	err := f.wal.Sync()
+	if cerr := f.wal.Close(); err == nil {
+		err = cerr
+	}
+	f.wal = nil
+	if uerr := f.lock.Unlock(); err == nil {
+		err = uerr
+	}
+	return err
+}
+
+func readSnapshotFile(dir string) (snapshotFile, error) {
+	snap := snapshotFile{}
+
+	b, err := os.ReadFile(filepath.Join(dir, snapshotName))
+	if errors.Is(err, os.ErrNotExist) {
+		return snap, nil
+	}
+	if err != nil {
+		return snap, err
+	}
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return snap, fmt.Errorf("snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+// writeSnapshotFile replaces the snapshot atomically.
+func writeSnapshotFile(dir string, snap snapshotFile) error {
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	return pkg.WriteFileAtomic(filepath.Join(dir, snapshotName), b)
+}
@@ -0,0 +1,269 @@
+package storagemem
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+)
+
+func openFile(t *testing.T, dir string, fo FileOptions) *InMemoryStore {
+	t.Helper()
+
+	s, err := OpenFileStore(dir, fo)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestFileStore_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	s, _ := OpenFileStore(dir, FileOptions{})
+	a, _ := s.Create(ctx, todo.Todo{Title: "a"})
+	b, _ := s.Create(ctx, todo.Todo{Title: "b"})
+	if err := s.Remove(ctx, a.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	s = openFile(t, dir, FileOptions{})
+	if _, err := s.Get(ctx, a.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Get() removed err = %v, want %v", err, todo.ErrNotFound)
+	}
+	got, err := s.Get(ctx, b.ID)
+	if err != nil || got.Title != "b" {
+		t.Fatalf("Get() = %v, %v; want b", got, err)
+	}
+
+	c, _ := s.Create(ctx, todo.Todo{Title: "c"})
+	if c.ID != 3 {
+		t.Fatalf("Create() id = %d, want 3", c.ID)
+	}
+}
+
+func TestFileStore_TornTailTruncated(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	s, _ := OpenFileStore(dir, FileOptions{})
+	s.Create(ctx, todo.Todo{Title: "a"})
+	s.Create(ctx, todo.Todo{Title: "b"})
+	s.Close()
+
+	path := walPath(dir, 0)
+	info, _ := os.Stat(path)
+	// cut the last record in half
+	if err := os.Truncate(path, info.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openFile(t, dir, FileOptions{})
+	if s.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", s.Len())
+	}
+	if _, err := s.Create(ctx, todo.Todo{Title: "c"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	s.Close()
+
+	s = openFile(t, dir, FileOptions{})
+	if s.Len() != 2 {
+		t.Fatalf("Len() after reopen = %d, want 2", s.Len())
+	}
+}
+
+func TestFileStore_ChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	s, _ := OpenFileStore(dir, FileOptions{})
+	s.Create(ctx, todo.Todo{Title: "a"})
+	s.Create(ctx, todo.Todo{Title: "b"})
+	s.Close()
+
+	path := walPath(dir, 0)
+	b, _ := os.ReadFile(path)
+	b[len(b)-3] ^= 0xff
+	os.WriteFile(path, b, 0o644)
+
+	s = openFile(t, dir, FileOptions{})
+	if s.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", s.Len())
+	}
+}
+
+func TestFileStore_Locked(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := OpenFileStore(dir, FileOptions{})
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	if _, err := OpenFileStore(dir, FileOptions{}); !errors.Is(err, pkg.ErrLocked) {
+		t.Fatalf("OpenFileStore() err = %v, want %v", err, pkg.ErrLocked)
+	}
+	s.Close()
+
+	openFile(t, dir, FileOptions{})
+}
+
+func TestFileStore_CorruptionFailsOpen(t *testing.T) {
+	put := func(id int64) []byte {
+		b, err := encodeRecord(record{Op: opPut, Todo: &todo.Todo{ID: id, Title: "t"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	corrupt := func(b []byte) []byte {
+		b[len(b)-3] ^= 0xff
+		return b
+	}
+
+	for _, tc := range []struct {
+		name  string
+		files map[int64][]byte
+	}{
+		{"middle of the log", map[int64][]byte{
+			0: slices.Concat(put(1), corrupt(put(2)), put(3)),
+		}},
+		{"tail of an older generation", map[int64][]byte{
+			0: slices.Concat(put(1), corrupt(put(2))),
+			1: put(3),
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for gen, b := range tc.files {
+				if err := os.WriteFile(walPath(dir, gen), b, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := OpenFileStore(dir, FileOptions{}); !errors.Is(err, ErrCorruptWAL) {
+				t.Fatalf("OpenFileStore() err = %v, want %v", err, ErrCorruptWAL)
+			}
+			// nothing was cut
+			if b, _ := os.ReadFile(walPath(dir, 0)); len(b) != len(tc.files[0]) {
+				t.Fatalf("WAL size = %d, want %d", len(b), len(tc.files[0]))
+			}
+		})
+	}
+}
+
+func TestFileStore_Compaction(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	s, _ := OpenFileStore(dir, FileOptions{Sync: SyncNever, CompactEvery: 1000})
+	for i := 0; i < 10; i++ {
+		s.Create(ctx, todo.Todo{Title: "t"})
+	}
+	s.Remove(ctx, 1)
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	s.Create(ctx, todo.Todo{Title: "after"})
+	s.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, snapshotName)); err != nil {
+		t.Fatalf("snapshot missing: %v", err)
+	}
+	gens, _ := listWALs(dir)
+	if len(gens) != 1 || gens[0] != 1 {
+		t.Fatalf("wal generations = %v, want [1]", gens)
+	}
+
+	s = openFile(t, dir, FileOptions{})
+	if s.Len() != 10 {
+		t.Fatalf("Len() = %d, want 10", s.Len())
+	}
+	if _, err := s.Get(ctx, 1); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Get() removed err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if got, _ := s.Get(ctx, 11); got.Title != "after" {
+		t.Fatalf("Get() = %v, want after", got)
+	}
+}
+
+func TestFileStore_ClosedStoreRejectsWrites(t *testing.T) {
+	s, _ := OpenFileStore(t.TempDir(), FileOptions{Sync: SyncInterval})
+	s.Close()
+
+	if _, err := s.Create(context.Background(), todo.Todo{Title: "t"}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Create() err = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	for in, want := range map[string]SyncPolicy{"": SyncAlways, "always": SyncAlways, "Interval": SyncInterval, "never": SyncNever} {
+		got, err := ParseSyncPolicy(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseSyncPolicy(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseSyncPolicy("sometimes"); err == nil {
+		t.Fatalf("ParseSyncPolicy() expected error")
+	}
+}
+
+func TestFileStore_TenantsSurviveCompaction(t *testing.T) {
+	dir := t.TempDir()
+	ctx := pkg.WithTenant(context.Background(), "acme")
+
+	s, _ := OpenFileStore(dir, FileOptions{})
+	a, _ := s.Create(ctx, todo.Todo{Title: "a"})
+	b, _ := s.Create(ctx, todo.Todo{Title: "b"})
+	s.Remove(ctx, b.ID)
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	s.Close()
+
+	s = openFile(t, dir, FileOptions{})
+	if _, err := s.Get(ctx, a.ID); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, err := s.Get(context.Background(), a.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Get() from default tenant err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if c, _ := s.Create(ctx, todo.Todo{Title: "c"}); c.ID != 3 {
+		t.Fatalf("Create() id = %d, want 3", c.ID)
+	}
+}
+
+func TestFileStore_LegacySnapshot(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `{"gen":0,"last_id":5,"items":[{"ID":4,"Title":"old","Status":"pending"}]}`
+	if err := os.WriteFile(filepath.Join(dir, snapshotName), []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := OpenFileStore(dir, FileOptions{})
+	old, err := s.Get(context.Background(), 4)
+	if err != nil || old.Title != "old" {
+		t.Fatalf("Get() = %v, %v; want old", old, err)
+	}
+	if !todo.ValidPublicID(old.PublicID) {
+		t.Fatalf("Get() public id = %q, want one backfilled", old.PublicID)
+	}
+	if c, _ := s.Create(context.Background(), todo.Todo{Title: "new"}); c.ID != 6 {
+		t.Fatalf("Create() id = %d, want 6", c.ID)
+	}
+	s.Close()
+
+	// the backfilled id is kept
+	s = openFile(t, dir, FileOptions{})
+	if id, err := s.Resolve(context.Background(), old.PublicID); err != nil || id != 4 {
+		t.Fatalf("Resolve() after reopen = %d, %v; want 4", id, err)
+	}
+}
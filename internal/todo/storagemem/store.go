@@ -5,34 +5,60 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"todo-api/internal/pkg"
 	"todo-api/internal/todo"
+	"todo-api/internal/todo/outbox"
 )
 
 type InMemoryStore struct {
 	mu     sync.RWMutex
-	items  map[int64]todo.Todo
-	lastID int64
+	items  map[string]map[int64]todo.Todo // by tenant
+	lastID map[string]int64               // by tenant
+	public map[string]map[string]int64    // by tenant, public id to id
+	outbox *outbox.Memory
+	file   *fileState // nil unless opened with OpenFileStore
+}
+
+type Option func(*InMemoryStore)
+
+// WithOutbox makes the store append a domain event to ob on every mutation.
+func WithOutbox(ob *outbox.Memory) Option {
+	return func(s *InMemoryStore) {
+		s.outbox = ob
+	}
 }
 
 // Ping implements todo.Repository.
 func (s *InMemoryStore) Ping(ctx context.Context) error {
-	return nil
+	return ctx.Err()
 }
 
 var _ todo.Repository = (*InMemoryStore)(nil)
 
-func NewInMemoryStore() *InMemoryStore {
-	return &InMemoryStore{
-		items:  make(map[int64]todo.Todo),
-		lastID: 0}
+func NewInMemoryStore(opts ...Option) *InMemoryStore {
+	s := &InMemoryStore{
+		items:  make(map[string]map[int64]todo.Todo),
+		lastID: make(map[string]int64),
+		public: make(map[string]map[string]int64)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *InMemoryStore) Create(ctx context.Context, t todo.Todo) (todo.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return todo.Todo{}, err
+	}
+
+	tenant := pkg.TenantFrom(ctx)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.lastID++
-	t.ID = s.lastID
+	t.ID = s.lastID[tenant] + 1
+	if t.PublicID == "" {
+		t.PublicID = todo.NewPublicID()
+	}
 	if strings.TrimSpace(t.Status) == "" {
 		t.Status = todo.StatusPending
 	} else {
@@ -41,47 +67,155 @@ func (s *InMemoryStore) Create(ctx context.Context, t todo.Todo) (todo.Todo, err
 	curTime := time.Now().UTC()
 	t.CreatedAt = curTime
 	t.UpdatedAt = curTime
-	s.items[s.lastID] = t
+
+	msg, err := s.event(tenant, t.ID, todo.EventCreated, todo.ToDTO(t))
+	if err != nil {
+		return todo.Todo{}, err
+	}
+	if err := s.persist(record{Op: opPut, Tenant: tenant, Todo: &t}); err != nil {
+		return todo.Todo{}, err
+	}
+	s.put(tenant, t)
+	s.emit(msg)
 	return t, nil
 }
 
 func (s *InMemoryStore) Get(ctx context.Context, id int64) (t todo.Todo, err error) {
+	if err := ctx.Err(); err != nil {
+		return todo.Todo{}, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	t, ok := s.items[id]
+	t, ok := s.items[pkg.TenantFrom(ctx)][id]
 	if !ok {
 		return todo.Todo{}, todo.ErrNotFound
 	}
 	return t, nil
 }
 
+// Resolve implements todo.Repository.
+func (s *InMemoryStore) Resolve(ctx context.Context, publicID string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.public[pkg.TenantFrom(ctx)][publicID]
+	if !ok {
+		return 0, todo.ErrNotFound
+	}
+	return id, nil
+}
+
 func (s *InMemoryStore) Remove(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tenant := pkg.TenantFrom(ctx)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, ok := s.items[id]
+	t, ok := s.items[tenant][id]
 	if !ok {
 		return todo.ErrNotFound
 	}
-	delete(s.items, id)
+	msg, err := s.event(tenant, id, todo.EventRemoved, todo.RemovedPayload{ID: t.PublicID})
+	if err != nil {
+		return err
+	}
+	if err := s.persist(record{Op: opDelete, Tenant: tenant, ID: id}); err != nil {
+		return err
+	}
+	s.del(tenant, id)
+	s.emit(msg)
 	return nil
 }
 
+// put stores t for tenant and advances its id counter. The caller must hold s.mu.
+func (s *InMemoryStore) put(tenant string, t todo.Todo) {
+	items, ok := s.items[tenant]
+	if !ok {
+		items = make(map[int64]todo.Todo)
+		s.items[tenant] = items
+	}
+	items[t.ID] = t
+	if t.ID > s.lastID[tenant] {
+		s.lastID[tenant] = t.ID
+	}
+	if t.PublicID == "" {
+		return
+	}
+	public, ok := s.public[tenant]
+	if !ok {
+		public = make(map[string]int64)
+		s.public[tenant] = public
+	}
+	public[t.PublicID] = t.ID
+}
+
+// del removes the todo id of tenant. The caller must hold s.mu.
+func (s *InMemoryStore) del(tenant string, id int64) {
+	if t, ok := s.items[tenant][id]; ok {
+		delete(s.public[tenant], t.PublicID)
+		delete(s.items[tenant], id)
+	}
+}
+
+// event builds the outbox message for a mutation, if the outbox is enabled.
+func (s *InMemoryStore) event(tenant string, id int64, typ string, payload any) (*outbox.Message, error) {
+	if s.outbox == nil {
+		return nil, nil
+	}
+	msg, err := outbox.NewMessage(tenant, id, typ, payload)
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// emit appends msg to the outbox. The caller must hold s.mu.
+func (s *InMemoryStore) emit(msg *outbox.Message) {
+	if msg != nil {
+		s.outbox.Append(*msg)
+	}
+}
+
+// Len returns the number of todos of all tenants.
 func (s *InMemoryStore) Len() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return len(s.items)
+	n := 0
+	for _, items := range s.items {
+		n += len(items)
+	}
+	return n
 }
 
+// Snapshot returns a copy of the todos of the default tenant.
 func (s *InMemoryStore) Snapshot() map[int64]todo.Todo {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	cp := make(map[int64]todo.Todo, len(s.items))
-	for k, v := range s.items {
+	cp := make(map[int64]todo.Todo, len(s.items[pkg.DefaultTenant]))
+	for k, v := range s.items[pkg.DefaultTenant] {
 		cp[k] = v
 	}
 	return cp
 }
+
+var _ todo.Transactor = (*InMemoryStore)(nil)
+
+// WithinTx implements todo.Transactor. The in-memory store has no
+// transactions: fn runs directly and changes made before an error are kept.
+func (s *InMemoryStore) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"sync"
 	"testing"
+	"todo-api/internal/pkg"
 	"todo-api/internal/todo"
+	"todo-api/internal/todo/outbox"
 )
 
 func TestCreation(t *testing.T) {
@@ -28,9 +30,9 @@ func TestCreationTodo(t *testing.T) {
 		t.Errorf("%s: expected no err, obtained %s", testName, err)
 	}
 
-	if todoOut.ID != store.lastID {
+	if todoOut.ID != store.lastID[pkg.DefaultTenant] {
 		t.Errorf("%s: ID mismatch: got %d, want %d",
-			testName, todoOut.ID, store.lastID)
+			testName, todoOut.ID, store.lastID[pkg.DefaultTenant])
 	}
 
 	snapshot := store.Snapshot()
@@ -53,9 +55,9 @@ func TestCreateGet_OK(t *testing.T) {
 		t.Errorf("%s: expected no err, obtained %s", testName, err)
 	}
 
-	if todoOut.ID != store.lastID {
+	if todoOut.ID != store.lastID[pkg.DefaultTenant] {
 		t.Errorf("%s: ID mismatch: got %d, want %d",
-			testName, todoOut.ID, store.lastID)
+			testName, todoOut.ID, store.lastID[pkg.DefaultTenant])
 	}
 
 	todoGet, err := store.Get(context.Background(), todoOut.ID)
@@ -85,9 +87,9 @@ func TestCreateGetAnother_NotFound(t *testing.T) {
 		t.Errorf("%s: expected no err, obtained %s", testName, err)
 	}
 
-	if todoOut.ID != store.lastID {
+	if todoOut.ID != store.lastID[pkg.DefaultTenant] {
 		t.Errorf("%s: ID mismatch: got %d, want %d",
-			testName, todoOut.ID, store.lastID)
+			testName, todoOut.ID, store.lastID[pkg.DefaultTenant])
 	}
 
 	_, err = store.Get(context.Background(), todoOut.ID+1)
@@ -109,9 +111,9 @@ func TestCreateDeleteAnotherGet_NotFound(t *testing.T) {
 		t.Errorf("%s: expected no err, obtained %s", testName, err)
 	}
 
-	if todoOut.ID != store.lastID {
+	if todoOut.ID != store.lastID[pkg.DefaultTenant] {
 		t.Errorf("%s: ID mismatch: got %d, want %d",
-			testName, todoOut.ID, store.lastID)
+			testName, todoOut.ID, store.lastID[pkg.DefaultTenant])
 	}
 
 	err = store.Remove(context.Background(), todoOut.ID+1)
@@ -142,9 +144,9 @@ func TestCreateDeleteGet_NotFound(t *testing.T) {
 		t.Errorf("%s: expected no err, obtained %s", testName, err)
 	}
 
-	if todoOut.ID != store.lastID {
+	if todoOut.ID != store.lastID[pkg.DefaultTenant] {
 		t.Errorf("%s: ID mismatch: got %d, want %d",
-			testName, todoOut.ID, store.lastID)
+			testName, todoOut.ID, store.lastID[pkg.DefaultTenant])
 	}
 
 	err = store.Remove(context.Background(), todoOut.ID)
@@ -171,9 +173,9 @@ func TestCreateDeleteGetAnother_OK(t *testing.T) {
 		t.Errorf("%s: expected no err, obtained %s", testName, err)
 	}
 
-	if todoOut.ID != store.lastID {
+	if todoOut.ID != store.lastID[pkg.DefaultTenant] {
 		t.Errorf("%s: ID mismatch: got %d, want %d",
-			testName, todoOut.ID, store.lastID)
+			testName, todoOut.ID, store.lastID[pkg.DefaultTenant])
 	}
 
 	err = store.Remove(context.Background(), todoOut.ID)
@@ -209,3 +211,38 @@ func TestConcurrent(t *testing.T) {
 		t.Errorf("%s: expected len of storage is %d, real %d", testName, threadNum, store.Len())
 	}
 }
+
+func TestOutbox_EventsAppended(t *testing.T) {
+	ob := outbox.NewMemory()
+	store := NewInMemoryStore(WithOutbox(ob))
+
+	out, err := store.Create(context.Background(), todo.Todo{Title: "title"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := store.Remove(context.Background(), out.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := store.Remove(context.Background(), out.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Remove() err = %v, want %v", err, todo.ErrNotFound)
+	}
+
+	var types []string
+	for ob.Len() > 0 {
+		ob.Process(context.Background(), 10, func(ctx context.Context, msgs []outbox.Message) []int64 {
+			var done []int64
+			for _, m := range msgs {
+				if m.TodoID != out.ID {
+					t.Errorf("todo id = %d, want %d", m.TodoID, out.ID)
+				}
+				types = append(types, m.Type)
+				done = append(done, m.ID)
+			}
+			return done
+		})
+	}
+
+	if len(types) != 2 || types[0] != todo.EventCreated || types[1] != todo.EventRemoved {
+		t.Fatalf("events = %v, want [%s %s]", types, todo.EventCreated, todo.EventRemoved)
+	}
+}
@@ -0,0 +1,204 @@
+package storagemem
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"todo-api/internal/todo"
+)
+
+// Every WAL record is an 8 byte header followed by a JSON payload.
+// The header holds the payload length and its CRC-32, both big endian.
+const walHeaderSize = 8
+
+const maxRecordSize = 16 << 20
+
+const (
+	opPut    = "put"
+	opDelete = "del"
+	// opSeq advances a tenant's id counter to ID.
+	opSeq = "seq"
+)
+
+type record struct {
+	Op string `json:"op"`
+	// Tenant is empty in records written before tenants existed,
+	// those belong to pkg.DefaultTenant.
+	Tenant string     `json:"tenant,omitempty"`
+	Todo   *todo.Todo `json:"todo,omitempty"`
+	ID     int64      `json:"id,omitempty"`
+}
+
+func walName(gen int64) string {
+	return fmt.Sprintf("wal-%016d.log", gen)
+}
+
+// listWALs returns the generations of the WAL files in dir in ascending order.
+func listWALs(dir string) ([]int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var gens []int64
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, "wal-") || !strings.HasSuffix(name, ".log") {
+			continue
+		}
+		gen, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, "wal-"), ".log"), 10, 64)
+		if err != nil {
+			continue
+		}
+		gens = append(gens, gen)
+	}
+	sort.Slice(gens, func(i, j int) bool { return gens[i] < gens[j] })
+	return gens, nil
+}
+
+func encodeRecord(r record) ([]byte, error) {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, walHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(b[4:8], crc32.ChecksumIEEE(payload))
+	copy(b[walHeaderSize:], payload)
+	return b, nil
+}
+
+var (
+	// errTornRecord is a record cut short by the end of the file.
+	errTornRecord = errors.New("torn record")
+	// ErrCorruptWAL is a damaged record followed by more data, which an
+	// interrupted write cannot explain.
+	ErrCorruptWAL = errors.New("corrupt write-ahead log")
+)
+
+// readRecord returns the next record and its size. A record with a bad
+// length, checksum or payload is returned as ErrCorruptWAL with the size
+// its header claims.
+func readRecord(r io.Reader) (record, int, error) {
+	var hdr [walHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return record{}, 0, tornIfShort(err)
+	}
+
+	size := binary.BigEndian.Uint32(hdr[0:4])
+	total := walHeaderSize + int(size)
+	if size > maxRecordSize {
+		return record{}, total, ErrCorruptWAL
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			return record{}, 0, errTornRecord
+		}
+		return record{}, 0, tornIfShort(err)
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(hdr[4:8]) {
+		return record{}, total, ErrCorruptWAL
+	}
+
+	rec := record{}
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return record{}, total, ErrCorruptWAL
+	}
+	return rec, total, nil
+}
+
+func tornIfShort(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return errTornRecord
+	}
+	return err
+}
+
+// replayWAL calls fn for every valid record in the file and returns the
+// number of records and the size of the valid part. A damaged last record,
+// one reaching the end of the file or followed by zeros only, is the
+// remainder of an interrupted write. In the newest generation, the one
+// written to when the store stopped, it is truncated. Damage anywhere else
+// is ErrCorruptWAL, as the records after it would be lost.
+func replayWAL(path string, newest bool, fn func(record)) (int, int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	r := bufio.NewReader(f)
+	var n int
+	var offset int64
+	for {
+		rec, size, err := readRecord(r)
+		if errors.Is(err, io.EOF) {
+			return n, offset, nil
+		}
+		if errors.Is(err, ErrCorruptWAL) && offset+int64(size) >= fi.Size() {
+			err = errTornRecord // the length reaches past the end
+		}
+		if errors.Is(err, ErrCorruptWAL) {
+			if zeros, zerr := zeroFrom(f, offset); zerr != nil {
+				return 0, 0, zerr
+			} else if zeros {
+				err = errTornRecord
+			}
+		}
+		if errors.Is(err, errTornRecord) && newest {
+			if err := f.Truncate(offset); err != nil {
+				return 0, 0, err
+			}
+			return n, offset, f.Sync()
+		}
+		if errors.Is(err, errTornRecord) || errors.Is(err, ErrCorruptWAL) {
+			return 0, 0, fmt.Errorf("%s: %w at offset %d", path, ErrCorruptWAL, offset)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		fn(rec)
+		n++
+		offset += int64(size)
+	}
+}
+
+// zeroFrom reports whether f holds only zero bytes from offset on, as file
+// systems may leave after a crash while the file was extended.
+func zeroFrom(f *os.File, offset int64) (bool, error) {
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := f.ReadAt(buf, offset)
+		for _, b := range buf[:n] {
+			if b != 0 {
+				return false, nil
+			}
+		}
+		offset += int64(n)
+		if errors.Is(err, io.EOF) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+func walPath(dir string, gen int64) string {
+	return filepath.Join(dir, walName(gen))
+}
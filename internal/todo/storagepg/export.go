@@ -0,0 +1,115 @@
+package storagepg
+
+import (
+	"context"
+	"database/sql"
+	"todo-api/internal/todo"
+)
+
+var (
+	_ todo.Exporter = (*PostgresStore)(nil)
+	_ todo.Importer = (*PostgresStore)(nil)
+)
+
+// Export implements todo.Exporter. Everything is read on the primary in one
+// repeatable read transaction, so the export is consistent. Tenants are
+// sorted bytewise like in the other backends.
+func (p *PostgresStore) Export(ctx context.Context, fn func(todo.Record) error) error {
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+	SELECT tenant_id, last_id
+	FROM tenant_sequences
+	ORDER BY tenant_id COLLATE "C"
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r := todo.Record{}
+		if err := rows.Scan(&r.Tenant, &r.LastID); err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+	SELECT tenant_id, id, public_id, title, description, status, created_at, updated_at
+	FROM todos
+	ORDER BY tenant_id COLLATE "C", id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r := todo.Record{Todo: &todo.Todo{}}
+		err := rows.Scan(
+			&r.Tenant,
+			&r.Todo.ID,
+			&r.Todo.PublicID,
+			&r.Todo.Title,
+			&r.Todo.Description,
+			&r.Todo.Status,
+			&r.Todo.CreatedAt,
+			&r.Todo.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Import implements todo.Importer. No outbox events are stored.
+// Inside WithinTx the caller's transaction is used.
+func (p *PostgresStore) Import(ctx context.Context, r todo.Record) error {
+	var q querier = p.db
+	if tx := p.txFrom(ctx); tx != nil {
+		q = tx
+	}
+
+	if r.Todo == nil {
+		_, err := q.ExecContext(ctx, `
+	INSERT INTO tenant_sequences (tenant_id, last_id)
+	VALUES($1, $2)
+	ON CONFLICT (tenant_id) DO UPDATE SET last_id = GREATEST(tenant_sequences.last_id, EXCLUDED.last_id)
+	`, r.Tenant, r.LastID)
+		return err
+	}
+
+	t := r.Todo
+	_, err := q.ExecContext(ctx, `
+	WITH seq AS (
+		INSERT INTO tenant_sequences (tenant_id, last_id)
+		VALUES($1, $2)
+		ON CONFLICT (tenant_id) DO UPDATE SET last_id = GREATEST(tenant_sequences.last_id, EXCLUDED.last_id)
+	)
+	INSERT INTO todos (tenant_id, id, public_id, title, description, status, created_at, updated_at)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (tenant_id, id) DO UPDATE SET
+		public_id = EXCLUDED.public_id,
+		title = EXCLUDED.title,
+		description = EXCLUDED.description,
+		status = EXCLUDED.status,
+		created_at = EXCLUDED.created_at,
+		updated_at = EXCLUDED.updated_at
+	`, r.Tenant, t.ID, t.PublicID, t.Title, t.Description, t.Status, t.CreatedAt, t.UpdatedAt)
+	return err
+}
@@ -0,0 +1,89 @@
+package storagepg
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"todo-api/internal/todo/outbox"
+)
+
+func insertMessage(ctx context.Context, q querier, m outbox.Message) error {
+	_, err := q.ExecContext(ctx, `
+	INSERT INTO outbox (tenant_id, todo_id, event_type, payload, created_at)
+	VALUES($1, $2, $3, $4, $5)
+	`, m.Tenant, m.TodoID, m.Type, m.Payload, m.CreatedAt)
+	return err
+}
+
+// Outbox is the reading side of the outbox table.
+// Several relays may poll it at once: claimed rows are locked with
+// FOR UPDATE SKIP LOCKED and only the oldest pending row of every todo is
+// eligible, so a todo's events are published one by one and in order.
+type Outbox struct {
+	db *sql.DB
+}
+
+var _ outbox.Store = (*Outbox)(nil)
+
+func NewOutbox(db *sql.DB) *Outbox {
+	return &Outbox{db: db}
+}
+
+// Process implements outbox.Store.
+func (o *Outbox) Process(ctx context.Context, limit int, fn func(ctx context.Context, msgs []outbox.Message) []int64) (int, error) {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+	SELECT o.id, o.tenant_id, o.todo_id, o.event_type, o.payload, o.created_at
+	FROM outbox o
+	WHERE o.delivered_at IS NULL
+	AND NOT EXISTS (
+		SELECT 1 FROM outbox p
+		WHERE p.tenant_id = o.tenant_id AND p.todo_id = o.todo_id
+		AND p.delivered_at IS NULL AND p.id < o.id
+	)
+	ORDER BY o.id
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var msgs []outbox.Message
+	for rows.Next() {
+		m := outbox.Message{}
+		if err := rows.Scan(&m.ID, &m.Tenant, &m.TodoID, &m.Type, &m.Payload, &m.CreatedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		msgs = append(msgs, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+
+	done := fn(ctx, msgs)
+	now := time.Now().UTC()
+	for _, id := range done {
+		if _, err := tx.ExecContext(ctx, `
+	UPDATE outbox SET delivered_at = $1
+	WHERE id = $2
+	`, now, id); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(done), nil
+}
@@ -0,0 +1,115 @@
+package storagepg
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+	"todo-api/internal/todo/outbox"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCreate_Outbox_SameTx(t *testing.T) {
+	db, mock, _ := newMock(t)
+	defer db.Close()
+	store := New(db, WithOutbox())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO todos`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).
+		WithArgs(pkg.DefaultTenant, int64(5), todo.EventCreated, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	got, err := store.Create(context.Background(), todo.Todo{Title: "T"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got.ID != 5 {
+		t.Fatalf("Create() id = %d, want 5", got.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCreate_Outbox_RollbackOnError(t *testing.T) {
+	db, mock, _ := newMock(t)
+	defer db.Close()
+	store := New(db, WithOutbox())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO todos`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).
+		WillReturnError(errors.New("outbox failed"))
+	mock.ExpectRollback()
+
+	if _, err := store.Create(context.Background(), todo.Todo{Title: "T"}); err == nil {
+		t.Fatalf("Create() expected error, got nil")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRemove_Outbox_NotFoundRollsBack(t *testing.T) {
+	db, mock, _ := newMock(t)
+	defer db.Close()
+	store := New(db, WithOutbox())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM todos`)).
+		WithArgs(pkg.DefaultTenant, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"public_id"}))
+	mock.ExpectRollback()
+
+	if err := store.Remove(context.Background(), 10); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Remove() err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestOutbox_Process_MarksDelivered(t *testing.T) {
+	db, mock, _ := newMock(t)
+	defer db.Close()
+	ob := NewOutbox(db)
+
+	now := time.Now().UTC()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "todo_id", "event_type", "payload", "created_at"}).
+			AddRow(1, "acme", 7, todo.EventCreated, []byte(`{}`), now).
+			AddRow(2, "acme", 8, todo.EventCreated, []byte(`{}`), now))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE outbox SET delivered_at`)).
+		WithArgs(sqlmock.AnyArg(), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := ob.Process(context.Background(), 10, func(ctx context.Context, msgs []outbox.Message) []int64 {
+		if len(msgs) != 2 {
+			t.Fatalf("got %d messages, want 2", len(msgs))
+		}
+		if msgs[0].Tenant != "acme" {
+			t.Fatalf("message tenant = %q, want acme", msgs[0].Tenant)
+		}
+		return []int64{msgs[0].ID}
+	})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("Process() = %d, want 1", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package storagepg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestOpenPool_AppliesConfig(t *testing.T) {
+	// nothing listens there, the pool connects lazily
+	pool, db, err := OpenPool(context.Background(), "postgres://u:p@127.0.0.1:1/db", PoolConfig{
+		MaxConns:               7,
+		MaxConnLifetime:        time.Minute,
+		StatementCacheCapacity: 64,
+		ApplicationName:        "todo-test",
+	})
+	if err != nil {
+		t.Fatalf("OpenPool() error = %v", err)
+	}
+	defer pool.Close()
+	defer db.Close()
+
+	cfg := pool.Config()
+	if cfg.MaxConns != 7 || cfg.MaxConnLifetime != time.Minute {
+		t.Fatalf("pool config max=%d lifetime=%v", cfg.MaxConns, cfg.MaxConnLifetime)
+	}
+	if cfg.ConnConfig.RuntimeParams["application_name"] != "todo-test" {
+		t.Fatalf("application_name = %q", cfg.ConnConfig.RuntimeParams["application_name"])
+	}
+	if cfg.ConnConfig.StatementCacheCapacity != 64 || cfg.ConnConfig.DefaultQueryExecMode != pgx.QueryExecModeCacheStatement {
+		t.Fatalf("statement cache = %d, mode = %v", cfg.ConnConfig.StatementCacheCapacity, cfg.ConnConfig.DefaultQueryExecMode)
+	}
+
+	if got := Stats(pool).MaxConns; got != 7 {
+		t.Fatalf("Stats().MaxConns = %d, want 7", got)
+	}
+}
+
+func TestOpenPool_NoStatementCache(t *testing.T) {
+	pool, db, err := OpenPool(context.Background(), "postgres://u:p@127.0.0.1:1/db", PoolConfig{})
+	if err != nil {
+		t.Fatalf("OpenPool() error = %v", err)
+	}
+	defer pool.Close()
+	defer db.Close()
+
+	if mode := pool.Config().ConnConfig.DefaultQueryExecMode; mode != pgx.QueryExecModeDescribeExec {
+		t.Fatalf("exec mode = %v, want describe_exec", mode)
+	}
+}
+
+func TestOpenPool_BadDSN(t *testing.T) {
+	if _, _, err := OpenPool(context.Background(), "postgres://%zz", PoolConfig{}); err == nil {
+		t.Fatalf("OpenPool() expected error")
+	}
+}
@@ -0,0 +1,97 @@
+package storagepg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+	"todo-api/internal/pkg"
+)
+
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// WithReplicas sends reads to the given read replicas in round-robin order.
+// Writes, reads inside WithinTx and reads of contexts marked with
+// pkg.WithReadPrimary go to the primary, as do all reads when no replica is
+// healthy.
+func WithReplicas(dbs ...*sql.DB) Option {
+	return func(p *PostgresStore) {
+		for _, db := range dbs {
+			r := &replica{db: db}
+			r.healthy.Store(true)
+			p.replicas = append(p.replicas, r)
+		}
+	}
+}
+
+// reader picks the connection for a read. The replica is nil when the read
+// goes to the primary.
+func (p *PostgresStore) reader(ctx context.Context) (querier, *replica) {
+	if tx := p.txFrom(ctx); tx != nil {
+		return tx, nil
+	}
+	if len(p.replicas) == 0 || pkg.ReadPrimary(ctx) {
+		return p.db, nil
+	}
+
+	start := p.next.Add(1) - 1
+	for i := range p.replicas {
+		r := p.replicas[(start+uint64(i))%uint64(len(p.replicas))]
+		if r.healthy.Load() {
+			return r.db, r
+		}
+	}
+	return p.db, nil
+}
+
+// read runs fn on a replica and repeats it on the primary if the replica fails.
+func (p *PostgresStore) read(ctx context.Context, fn func(q querier) error) error {
+	q, r := p.reader(ctx)
+	err := fn(q)
+	if r == nil || err == nil || errors.Is(err, sql.ErrNoRows) || ctx.Err() != nil {
+		return err
+	}
+
+	log.Printf("storagepg: replica read failed, using primary: %s", err)
+	r.healthy.Store(false)
+	return fn(p.db)
+}
+
+// CheckReplicas pings every replica and updates its health.
+func (p *PostgresStore) CheckReplicas(ctx context.Context) {
+	for _, r := range p.replicas {
+		err := r.db.PingContext(ctx)
+		if ok := err == nil; r.healthy.Swap(ok) != ok {
+			if ok {
+				log.Printf("storagepg: replica is back")
+			} else {
+				log.Printf("storagepg: replica is down: %s", err)
+			}
+		}
+	}
+}
+
+// RunReplicaHealthCheck calls CheckReplicas every interval until ctx is done.
+func (p *PostgresStore) RunReplicaHealthCheck(ctx context.Context, interval time.Duration) {
+	if len(p.replicas) == 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			cctx, cancel := context.WithTimeout(ctx, interval)
+			p.CheckReplicas(cctx)
+			cancel()
+		}
+	}
+}
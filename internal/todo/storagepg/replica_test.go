@@ -0,0 +1,142 @@
+package storagepg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+	"todo-api/internal/pkg"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var getQuery = regexp.QuoteMeta(`SELECT id, public_id, title, description, status, created_at, updated_at`)
+
+func newReplica(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, mock
+}
+
+func todoRows(id int64) *sqlmock.Rows {
+	now := time.Now().UTC()
+	return sqlmock.NewRows([]string{"id", "public_id", "title", "description", "status", "created_at", "updated_at"}).
+		AddRow(id, testPublicID, "T", nil, "pending", now, now)
+}
+
+func TestReplicas_RoundRobin(t *testing.T) {
+	primary, pmock := newReplica(t)
+	r1, m1 := newReplica(t)
+	r2, m2 := newReplica(t)
+	store := New(primary, WithReplicas(r1, r2))
+
+	m1.ExpectQuery(getQuery).WithArgs(pkg.DefaultTenant, 1).WillReturnRows(todoRows(1))
+	m2.ExpectQuery(getQuery).WithArgs(pkg.DefaultTenant, 2).WillReturnRows(todoRows(2))
+	m1.ExpectQuery(getQuery).WithArgs(pkg.DefaultTenant, 3).WillReturnRows(todoRows(3))
+	m2.ExpectQuery(getQuery).WithArgs(pkg.DefaultTenant, 4).WillReturnRows(todoRows(4))
+
+	for id := int64(1); id <= 4; id++ {
+		if _, err := store.Get(context.Background(), id); err != nil {
+			t.Fatalf("Get(%d) error = %v", id, err)
+		}
+	}
+
+	for _, m := range []sqlmock.Sqlmock{pmock, m1, m2} {
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	}
+}
+
+func TestReplicas_ReadPrimary(t *testing.T) {
+	primary, pmock := newReplica(t)
+	r1, m1 := newReplica(t)
+	store := New(primary, WithReplicas(r1))
+
+	pmock.ExpectQuery(getQuery).WithArgs(pkg.DefaultTenant, 1).WillReturnRows(todoRows(1))
+
+	if _, err := store.Get(pkg.WithReadPrimary(context.Background()), 1); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	for _, m := range []sqlmock.Sqlmock{pmock, m1} {
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	}
+}
+
+func TestReplicas_NotFoundDoesNotFailOver(t *testing.T) {
+	primary, pmock := newReplica(t)
+	r1, m1 := newReplica(t)
+	store := New(primary, WithReplicas(r1))
+
+	m1.ExpectQuery(getQuery).WithArgs(pkg.DefaultTenant, 1).WillReturnError(sql.ErrNoRows)
+
+	if _, err := store.Get(context.Background(), 1); err == nil {
+		t.Fatalf("Get() expected not found")
+	}
+	if !store.replicas[0].healthy.Load() {
+		t.Fatalf("replica marked unhealthy on not found")
+	}
+	for _, m := range []sqlmock.Sqlmock{pmock, m1} {
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	}
+}
+
+func TestReplicas_FailoverAndRecovery(t *testing.T) {
+	primary, pmock := newReplica(t)
+	r1, m1 := newReplica(t)
+	store := New(primary, WithReplicas(r1))
+
+	m1.ExpectQuery(getQuery).WithArgs(pkg.DefaultTenant, 1).WillReturnError(errors.New("connection refused"))
+	pmock.ExpectQuery(getQuery).WithArgs(pkg.DefaultTenant, 1).WillReturnRows(todoRows(1))
+	// unhealthy replica is skipped
+	pmock.ExpectQuery(getQuery).WithArgs(pkg.DefaultTenant, 2).WillReturnRows(todoRows(2))
+
+	if _, err := store.Get(context.Background(), 1); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, err := store.Get(context.Background(), 2); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	m1.ExpectPing()
+	store.CheckReplicas(context.Background())
+	m1.ExpectQuery(getQuery).WithArgs(pkg.DefaultTenant, 3).WillReturnRows(todoRows(3))
+	if _, err := store.Get(context.Background(), 3); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	for _, m := range []sqlmock.Sqlmock{pmock, m1} {
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	}
+}
+
+func TestReplicas_WritesGoToPrimary(t *testing.T) {
+	primary, pmock := newReplica(t)
+	r1, m1 := newReplica(t)
+	store := New(primary, WithReplicas(r1))
+
+	pmock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM todos`)).WithArgs(pkg.DefaultTenant, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"public_id"}).AddRow(testPublicID))
+
+	if err := store.Remove(context.Background(), 1); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	for _, m := range []sqlmock.Sqlmock{pmock, m1} {
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	}
+}
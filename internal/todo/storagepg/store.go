@@ -5,16 +5,76 @@ import (
 	"database/sql"
 	"errors"
 	"strings"
+	"sync/atomic"
 	"time"
+	"todo-api/internal/pkg"
 	"todo-api/internal/todo"
+	"todo-api/internal/todo/outbox"
 )
 
 type PostgresStore struct {
-	db *sql.DB
+	db        *sql.DB
+	outbox    bool
+	isolation sql.IsolationLevel
+	retries   int
+	replicas  []*replica
+	next      atomic.Uint64 // round-robin position in replicas
 }
 
-func New(db *sql.DB) *PostgresStore {
-	return &PostgresStore{db: db}
+type Option func(*PostgresStore)
+
+// WithOutbox makes every mutation insert a domain event into the outbox table
+// in the same transaction.
+func WithOutbox() Option {
+	return func(p *PostgresStore) {
+		p.outbox = true
+	}
+}
+
+func New(db *sql.DB, opts ...Option) *PostgresStore {
+	p := &PostgresStore{db: db, retries: defaultTxRetries}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// mutate runs fn and, when the outbox is enabled, stores the event fn returns
+// within the same transaction. Inside WithinTx the caller's transaction is used.
+func (p *PostgresStore) mutate(ctx context.Context, fn func(q querier) (outbox.Message, error)) error {
+	if tx := p.txFrom(ctx); tx != nil {
+		msg, err := fn(tx)
+		if err != nil || !p.outbox {
+			return err
+		}
+		return insertMessage(ctx, tx, msg)
+	}
+	if !p.outbox {
+		_, err := fn(p.db)
+		return err
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	msg, err := fn(tx)
+	if err != nil {
+		return err
+	}
+	if err := insertMessage(ctx, tx, msg); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // Ping implements todo.Repository.
@@ -32,12 +92,30 @@ func (p *PostgresStore) Create(ctx context.Context, t todo.Todo) (todo.Todo, err
 	now := time.Now().UTC()
 	t.CreatedAt = now
 	t.UpdatedAt = now
+	if t.PublicID == "" {
+		t.PublicID = todo.NewPublicID()
+	}
 
-	err := p.db.QueryRowContext(ctx, `
-	INSERT INTO todos (title, description, status, created_at, updated_at)
-	VALUES($1, $2, $3, $4, $5)
+	tenant := pkg.TenantFrom(ctx)
+	err := p.mutate(ctx, func(q querier) (outbox.Message, error) {
+		// ids come from a per-tenant counter, so they tell nothing about
+		// the number of todos of other tenants
+		err := q.QueryRowContext(ctx, `
+	WITH seq AS (
+		INSERT INTO tenant_sequences (tenant_id, last_id)
+		VALUES($1, 1)
+		ON CONFLICT (tenant_id) DO UPDATE SET last_id = tenant_sequences.last_id + 1
+		RETURNING last_id
+	)
+	INSERT INTO todos (tenant_id, id, public_id, title, description, status, created_at, updated_at)
+	SELECT $1, last_id, $2, $3, $4, $5, $6, $7 FROM seq
 	RETURNING id
-	`, t.Title, t.Description, t.Status, t.CreatedAt, t.UpdatedAt).Scan(&t.ID)
+	`, tenant, t.PublicID, t.Title, t.Description, t.Status, t.CreatedAt, t.UpdatedAt).Scan(&t.ID)
+		if err != nil {
+			return outbox.Message{}, err
+		}
+		return outbox.NewMessage(tenant, t.ID, todo.EventCreated, todo.ToDTO(t))
+	})
 	if err != nil {
 		return todo.Todo{}, err
 	}
@@ -51,18 +129,21 @@ func (p *PostgresStore) Get(ctx context.Context, id int64) (todo.Todo, error) {
 	}
 
 	res := todo.Todo{}
-	err := p.db.QueryRowContext(ctx, `
-	SELECT id, title, description, status, created_at, updated_at 
+	err := p.read(ctx, func(q querier) error {
+		return q.QueryRowContext(ctx, `
+	SELECT id, public_id, title, description, status, created_at, updated_at 
 	FROM todos 
-	WHERE id = $1
-	`, id).Scan(
-		&res.ID,
-		&res.Title,
-		&res.Description,
-		&res.Status,
-		&res.CreatedAt,
-		&res.UpdatedAt,
-	)
+	WHERE tenant_id = $1 AND id = $2
+	`, pkg.TenantFrom(ctx), id).Scan(
+			&res.ID,
+			&res.PublicID,
+			&res.Title,
+			&res.Description,
+			&res.Status,
+			&res.CreatedAt,
+			&res.UpdatedAt,
+		)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return todo.Todo{}, todo.ErrNotFound
@@ -73,30 +154,49 @@ func (p *PostgresStore) Get(ctx context.Context, id int64) (todo.Todo, error) {
 	return res, nil
 }
 
+// Resolve implements todo.Repository.
+func (p *PostgresStore) Resolve(ctx context.Context, publicID string) (int64, error) {
+	if !todo.ValidPublicID(publicID) {
+		return 0, todo.ErrNotFound
+	}
+
+	var id int64
+	err := p.read(ctx, func(q querier) error {
+		return q.QueryRowContext(ctx, `
+	SELECT id
+	FROM todos
+	WHERE tenant_id = $1 AND public_id = $2
+	`, pkg.TenantFrom(ctx), publicID).Scan(&id)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, todo.ErrNotFound
+	}
+	return id, err
+}
+
 // Remove implements todo.Repository.
 func (p *PostgresStore) Remove(ctx context.Context, id int64) error {
 	if id <= 0 {
 		return todo.ErrNotFound
 	}
 
-	res, err := p.db.ExecContext(ctx, `
+	tenant := pkg.TenantFrom(ctx)
+	return p.mutate(ctx, func(q querier) (outbox.Message, error) {
+		var publicID string
+		err := q.QueryRowContext(ctx, `
 	DELETE FROM todos 
-	WHERE id = $1
-	`, id)
-
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
+	WHERE tenant_id = $1 AND id = $2
+	RETURNING public_id
+	`, tenant, id).Scan(&publicID)
 
-	if rowsAffected == 0 {
-		return todo.ErrNotFound
-	}
-	return nil
+		if errors.Is(err, sql.ErrNoRows) {
+			return outbox.Message{}, todo.ErrNotFound
+		}
+		if err != nil {
+			return outbox.Message{}, err
+		}
+		return outbox.NewMessage(tenant, id, todo.EventRemoved, todo.RemovedPayload{ID: publicID})
+	})
 }
 
 var _ todo.Repository = (*PostgresStore)(nil)
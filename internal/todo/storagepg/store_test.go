@@ -7,11 +7,14 @@ import (
 	"regexp"
 	"testing"
 	"time"
+	"todo-api/internal/pkg"
 	"todo-api/internal/todo"
 
 	"github.com/DATA-DOG/go-sqlmock"
 )
 
+const testPublicID = "01890a5d-ac96-774b-bcce-b302099a8057"
+
 func newMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock, *PostgresStore) {
 	t.Helper() // to indicate4 that it is a helper function
 
@@ -42,8 +45,8 @@ func TestCreate_OK(t *testing.T) {
 	defer db.Close()
 
 	q := regexp.QuoteMeta(`
-	INSERT INTO todos (title, description, status, created_at, updated_at)
-	VALUES($1, $2, $3, $4, $5)
+	INSERT INTO todos (tenant_id, id, public_id, title, description, status, created_at, updated_at)
+	SELECT $1, last_id, $2, $3, $4, $5, $6, $7 FROM seq
 	RETURNING id
 	`)
 
@@ -51,6 +54,8 @@ func TestCreate_OK(t *testing.T) {
 	desc := "Desc"
 	mock.ExpectQuery(q).
 		WithArgs(
+			pkg.DefaultTenant,
+			sqlmock.AnyArg(),
 			"My title",
 			desc,
 			todo.StatusPending,
@@ -92,14 +97,14 @@ func TestCreate_DBError(t *testing.T) {
 	defer db.Close()
 
 	q := regexp.QuoteMeta(`
-	INSERT INTO todos (title, description, status, created_at, updated_at)
-	VALUES($1, $2, $3, $4, $5)
+	INSERT INTO todos (tenant_id, id, public_id, title, description, status, created_at, updated_at)
+	SELECT $1, last_id, $2, $3, $4, $5, $6, $7 FROM seq
 	RETURNING id
 	`)
 
 	desc := "D"
 	mock.ExpectQuery(q).
-		WithArgs("T", desc, "Done", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(pkg.DefaultTenant, sqlmock.AnyArg(), "T", desc, "Done", sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnError(errors.New("db failed!"))
 
 	_, err := store.Create(context.Background(), todo.Todo{
@@ -118,24 +123,24 @@ func TestGet_OK(t *testing.T) {
 	defer db.Close()
 
 	q := regexp.QuoteMeta(`
-		SELECT id, title, description, status, created_at, updated_at
+		SELECT id, public_id, title, description, status, created_at, updated_at
 		FROM todos
-		WHERE id = $1
+		WHERE tenant_id = $1 AND id = $2
 	`)
 
 	now := time.Now().UTC()
 
-	rows := sqlmock.NewRows([]string{"id", "title", "description", "status", "created_at", "updated_at"}).
-		AddRow(7, "T", "D", "pending", now, now)
+	rows := sqlmock.NewRows([]string{"id", "public_id", "title", "description", "status", "created_at", "updated_at"}).
+		AddRow(7, testPublicID, "T", "D", "pending", now, now)
 
-	mock.ExpectQuery(q).WithArgs(7).WillReturnRows(rows)
+	mock.ExpectQuery(q).WithArgs(pkg.DefaultTenant, 7).WillReturnRows(rows)
 
 	got, err := store.Get(context.Background(), 7)
 	if err != nil {
 		t.Fatalf("Get() error = %v", err)
 	}
 
-	if got.ID != 7 || got.Title != "T" || *got.Description != "D" || got.Status != "pending" {
+	if got.ID != 7 || got.PublicID != testPublicID || got.Title != "T" || *got.Description != "D" || got.Status != "pending" {
 		t.Fatalf("Get() unexpected todo: %v", got)
 	}
 
@@ -149,12 +154,12 @@ func TestGet_NotFound(t *testing.T) {
 	defer db.Close()
 
 	q := regexp.QuoteMeta(`
-		SELECT id, title, description, status, created_at, updated_at
+		SELECT id, public_id, title, description, status, created_at, updated_at
 		FROM todos
-		WHERE id = $1
+		WHERE tenant_id = $1 AND id = $2
 	`)
 
-	mock.ExpectQuery(q).WithArgs(999).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(q).WithArgs(pkg.DefaultTenant, 999).WillReturnError(sql.ErrNoRows)
 
 	_, err := store.Get(context.Background(), 999)
 	if !errors.Is(err, todo.ErrNotFound) {
@@ -162,6 +167,57 @@ func TestGet_NotFound(t *testing.T) {
 	}
 }
 
+func TestGet_OtherTenant_NotFound(t *testing.T) {
+	db, mock, store := newMock(t)
+	defer db.Close()
+
+	q := regexp.QuoteMeta(`
+		SELECT id, public_id, title, description, status, created_at, updated_at
+		FROM todos
+		WHERE tenant_id = $1 AND id = $2
+	`)
+
+	mock.ExpectQuery(q).WithArgs("acme", 7).WillReturnError(sql.ErrNoRows)
+
+	ctx := pkg.WithTenant(context.Background(), "acme")
+	if _, err := store.Get(ctx, 7); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Get() err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	db, mock, store := newMock(t)
+	defer db.Close()
+
+	q := regexp.QuoteMeta(`
+	SELECT id
+	FROM todos
+	WHERE tenant_id = $1 AND public_id = $2
+	`)
+	mock.ExpectQuery(q).WithArgs(pkg.DefaultTenant, testPublicID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(q).WithArgs(pkg.DefaultTenant, testPublicID).
+		WillReturnError(sql.ErrNoRows)
+
+	id, err := store.Resolve(context.Background(), testPublicID)
+	if err != nil || id != 7 {
+		t.Fatalf("Resolve() = %d, %v; want 7", id, err)
+	}
+	if _, err := store.Resolve(context.Background(), testPublicID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Resolve() err = %v, want %v", err, todo.ErrNotFound)
+	}
+	// malformed ids never reach the database
+	if _, err := store.Resolve(context.Background(), "1"); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Resolve(1) err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
 func TestGet_InvalidID(t *testing.T) {
 	db, _, store := newMock(t)
 	defer db.Close()
@@ -176,13 +232,14 @@ func TestRemove_OK(t *testing.T) {
 	defer db.Close()
 
 	q := regexp.QuoteMeta(`
-	DELETE FROM todos 
-	WHERE id = $1
+	DELETE FROM todos
+	WHERE tenant_id = $1 AND id = $2
+	RETURNING public_id
 	`)
 
-	mock.ExpectExec(q).
-		WithArgs(10).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(q).
+		WithArgs(pkg.DefaultTenant, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"public_id"}).AddRow(testPublicID))
 
 	err := store.Remove(context.Background(), 10)
 	if err != nil {
@@ -200,12 +257,13 @@ func TestRemove_NotFound(t *testing.T) {
 
 	q := regexp.QuoteMeta(`
 	DELETE FROM todos
-	WHERE id = $1
+	WHERE tenant_id = $1 AND id = $2
+	RETURNING public_id
 	`)
 
-	mock.ExpectExec(q).
-		WithArgs(10).
-		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(q).
+		WithArgs(pkg.DefaultTenant, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"public_id"}))
 
 	err := store.Remove(context.Background(), 10)
 	if !errors.Is(err, todo.ErrNotFound) {
@@ -227,12 +285,13 @@ func TestRemove_DBError(t *testing.T) {
 	defer db.Close()
 
 	q := regexp.QuoteMeta(`
-	DELETE FROM todos 
-	WHERE id = $1
+	DELETE FROM todos
+	WHERE tenant_id = $1 AND id = $2
+	RETURNING public_id
 	`)
 
-	mock.ExpectExec(q).
-		WithArgs(10).
+	mock.ExpectQuery(q).
+		WithArgs(pkg.DefaultTenant, 10).
 		WillReturnError(errors.New("db down"))
 
 	err := store.Remove(context.Background(), 10)
@@ -240,3 +299,21 @@ func TestRemove_DBError(t *testing.T) {
 		t.Fatalf("Remove() want error, got nil")
 	}
 }
+
+func TestImport_Todo(t *testing.T) {
+	db, mock, store := newMock(t)
+	defer db.Close()
+
+	now := time.Now().UTC()
+	in := todo.Todo{ID: 7, PublicID: testPublicID, Title: "T", Status: "done", CreatedAt: now, UpdatedAt: now}
+	mock.ExpectExec(regexp.QuoteMeta(`ON CONFLICT (tenant_id, id) DO UPDATE SET`)).
+		WithArgs("acme", int64(7), testPublicID, "T", nil, "done", now, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := store.Import(context.Background(), todo.Record{Tenant: "acme", Todo: &in}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectation: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package storagepg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+	"todo-api/internal/todo"
+)
+
+var _ todo.Transactor = (*PostgresStore)(nil)
+
+const (
+	defaultTxRetries = 3
+	txRetryBaseDelay = 10 * time.Millisecond
+)
+
+// WithTxIsolation sets the isolation level of transactions started by WithinTx.
+func WithTxIsolation(level sql.IsolationLevel) Option {
+	return func(p *PostgresStore) {
+		p.isolation = level
+	}
+}
+
+// WithTxRetries sets how many times WithinTx retries a transaction that failed
+// with a serialization failure or a deadlock.
+func WithTxRetries(n int) Option {
+	return func(p *PostgresStore) {
+		p.retries = n
+	}
+}
+
+type txKey struct{}
+
+type txValue struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+// txFrom returns the transaction of this store's database carried by ctx.
+func (p *PostgresStore) txFrom(ctx context.Context) *sql.Tx {
+	if v, ok := ctx.Value(txKey{}).(txValue); ok && v.db == p.db {
+		return v.tx
+	}
+	return nil
+}
+
+// WithinTx implements todo.Transactor.
+// Serialization failures and deadlocks of the outermost transaction are
+// retried with backoff, so fn must be safe to run more than once.
+func (p *PostgresStore) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p.txFrom(ctx) != nil {
+		return fn(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := p.runTx(ctx, fn)
+		if err == nil || !isRetryable(err) || attempt >= p.retries {
+			return err
+		}
+
+		delay := txRetryBaseDelay << attempt
+		delay += rand.N(delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (p *PostgresStore) runTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{Isolation: p.isolation})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, txValue{db: p.db, tx: tx})); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isRetryable reports a serialization failure (40001) or a deadlock (40P01).
+func isRetryable(err error) bool {
+	var st interface{ SQLState() string }
+	if !errors.As(err, &st) {
+		return false
+	}
+	switch st.SQLState() {
+	case "40001", "40P01":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package storagepg
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type sqlStateErr string
+
+func (e sqlStateErr) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateErr) SQLState() string { return string(e) }
+
+func TestWithinTx_CommitsAll(t *testing.T) {
+	db, mock, store := newMock(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO todos`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM todos`)).
+		WithArgs(pkg.DefaultTenant, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"public_id"}).AddRow(testPublicID))
+	mock.ExpectCommit()
+
+	err := store.WithinTx(context.Background(), func(ctx context.Context) error {
+		if _, err := store.Create(ctx, todo.Todo{Title: "T"}); err != nil {
+			return err
+		}
+		return store.Remove(ctx, 2)
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestWithinTx_RollbackOnError(t *testing.T) {
+	db, mock, store := newMock(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO todos`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM todos`)).
+		WithArgs(pkg.DefaultTenant, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"public_id"}))
+	mock.ExpectRollback()
+
+	err := store.WithinTx(context.Background(), func(ctx context.Context) error {
+		if _, err := store.Create(ctx, todo.Todo{Title: "T"}); err != nil {
+			return err
+		}
+		return store.Remove(ctx, 2)
+	})
+	if !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("WithinTx() err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestWithinTx_NestedJoins(t *testing.T) {
+	db, mock, store := newMock(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	calls := 0
+	err := store.WithinTx(context.Background(), func(ctx context.Context) error {
+		return store.WithinTx(ctx, func(ctx context.Context) error {
+			calls++
+			return nil
+		})
+	})
+	if err != nil || calls != 1 {
+		t.Fatalf("WithinTx() = %v, calls = %d", err, calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestWithinTx_RetriesSerializationFailure(t *testing.T) {
+	db, mock, store := newMock(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO todos`)).WillReturnError(sqlStateErr("40001"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO todos`)).WillReturnError(sqlStateErr("40P01"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO todos`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	attempts := 0
+	err := store.WithinTx(context.Background(), func(ctx context.Context) error {
+		attempts++
+		_, err := store.Create(ctx, todo.Todo{Title: "T"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() error = %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("attempts = %d, want 3", attempts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestWithinTx_GivesUpAfterRetries(t *testing.T) {
+	db, mock, _ := newMock(t)
+	defer db.Close()
+	store := New(db, WithTxRetries(1))
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+	}
+
+	attempts := 0
+	err := store.WithinTx(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return sqlStateErr("40001")
+	})
+	if !isRetryable(err) || attempts != 2 {
+		t.Fatalf("WithinTx() = %v after %d attempts, want serialization failure after 2", err, attempts)
+	}
+}
+
+func TestWithinTx_NoRetryOnOtherErrors(t *testing.T) {
+	db, mock, store := newMock(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	attempts := 0
+	store.WithinTx(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return sqlStateErr("23505")
+	})
+	if attempts != 1 {
+		t.Fatalf("attempts = %d, want 1", attempts)
+	}
+}
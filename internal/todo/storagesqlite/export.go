@@ -0,0 +1,118 @@
+package storagesqlite
+
+import (
+	"context"
+	"todo-api/internal/todo"
+)
+
+var (
+	_ todo.Exporter = (*SQLiteStore)(nil)
+	_ todo.Importer = (*SQLiteStore)(nil)
+)
+
+// Export implements todo.Exporter. Everything is read in one transaction,
+// so the export is consistent.
+func (s *SQLiteStore) Export(ctx context.Context, fn func(todo.Record) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+	SELECT tenant_id, last_id
+	FROM tenant_sequences
+	ORDER BY tenant_id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r := todo.Record{}
+		if err := rows.Scan(&r.Tenant, &r.LastID); err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+	SELECT tenant_id, id, public_id, title, description, status, created_at, updated_at
+	FROM todos
+	ORDER BY tenant_id, id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r := todo.Record{Todo: &todo.Todo{}}
+		err := rows.Scan(
+			&r.Tenant,
+			&r.Todo.ID,
+			&r.Todo.PublicID,
+			&r.Todo.Title,
+			&r.Todo.Description,
+			&r.Todo.Status,
+			&r.Todo.CreatedAt,
+			&r.Todo.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		r.Todo.CreatedAt = r.Todo.CreatedAt.UTC()
+		r.Todo.UpdatedAt = r.Todo.UpdatedAt.UTC()
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Import implements todo.Importer.
+func (s *SQLiteStore) Import(ctx context.Context, r todo.Record) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	lastID := r.LastID
+	if r.Todo != nil {
+		lastID = r.Todo.ID
+	}
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO tenant_sequences (tenant_id, last_id)
+	VALUES(?, ?)
+	ON CONFLICT (tenant_id) DO UPDATE SET last_id = max(last_id, excluded.last_id)
+	`, r.Tenant, lastID)
+	if err != nil {
+		return err
+	}
+
+	if t := r.Todo; t != nil {
+		_, err = tx.ExecContext(ctx, `
+	INSERT INTO todos (tenant_id, id, public_id, title, description, status, created_at, updated_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (tenant_id, id) DO UPDATE SET
+		public_id = excluded.public_id,
+		title = excluded.title,
+		description = excluded.description,
+		status = excluded.status,
+		created_at = excluded.created_at,
+		updated_at = excluded.updated_at
+	`, r.Tenant, t.ID, t.PublicID, t.Title, t.Description, t.Status, t.CreatedAt, t.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,29 @@
+CREATE TABLE todos_new (
+    tenant_id TEXT NOT NULL,
+    id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    status TEXT NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (tenant_id, id)
+);
+INSERT INTO todos_new (tenant_id, id, title, description, status, created_at, updated_at)
+SELECT 'default', id, title, description, status, created_at, updated_at FROM todos;
+
+-- ids are counted per tenant, starting where AUTOINCREMENT stopped
+CREATE TABLE IF NOT EXISTS tenant_sequences (
+    tenant_id TEXT PRIMARY KEY,
+    last_id INTEGER NOT NULL
+);
+INSERT INTO tenant_sequences (tenant_id, last_id)
+SELECT 'default', seq FROM sqlite_sequence WHERE name = 'todos';
+
+DROP TABLE todos;
+ALTER TABLE todos_new RENAME TO todos;
+
+ALTER TABLE outbox ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+DROP INDEX IF EXISTS outbox_pending_idx;
+CREATE INDEX IF NOT EXISTS outbox_pending_idx
+    ON outbox (tenant_id, todo_id, id)
+    WHERE delivered_at IS NULL;
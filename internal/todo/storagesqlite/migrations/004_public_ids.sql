@@ -0,0 +1,40 @@
+-- rebuilt so that public_id is NOT NULL, as in Postgres
+CREATE TABLE todos_new (
+    tenant_id TEXT NOT NULL,
+    id INTEGER NOT NULL,
+    public_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    status TEXT NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (tenant_id, id)
+);
+
+-- backfill with UUIDv7 built from created_at, random bits make them unique.
+-- The driver stores times as "2006-01-02 15:04:05.999999999 -0700 MST",
+-- julianday needs "2006-01-02 15:04:05.999999999-07:00". Only date
+-- functions of every SQLite version are used.
+INSERT INTO todos_new (tenant_id, id, public_id, title, description, status, created_at, updated_at)
+SELECT tenant_id, id,
+    substr(ts, 1, 8) || '-' || substr(ts, 9, 4) || '-7' ||
+    substr(lower(hex(randomblob(2))), 2, 3) || '-' ||
+    substr('89ab', 1 + abs(random() % 4), 1) || substr(lower(hex(randomblob(2))), 2, 3) || '-' ||
+    lower(hex(randomblob(6))),
+    title, description, status, created_at, updated_at
+FROM (
+    SELECT *, printf('%012x', CAST(round((coalesce(
+        julianday(substr(raw, 1, sp - 1) || substr(raw, sp + 1, 3) || ':' || substr(raw, sp + 4, 2)),
+        julianday(raw),
+        julianday('now')) - 2440587.5) * 86400000) AS INTEGER)) AS ts
+    FROM (
+        SELECT *, CAST(created_at AS TEXT) AS raw,
+            11 + instr(substr(CAST(created_at AS TEXT), 12), ' ') AS sp
+        FROM todos
+    )
+);
+
+DROP TABLE todos;
+ALTER TABLE todos_new RENAME TO todos;
+
+CREATE UNIQUE INDEX IF NOT EXISTS todos_public_id_idx ON todos (tenant_id, public_id);
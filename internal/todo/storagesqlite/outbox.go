@@ -0,0 +1,94 @@
+package storagesqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"todo-api/internal/todo/outbox"
+)
+
+func insertMessage(ctx context.Context, tx *sql.Tx, m outbox.Message) error {
+	_, err := tx.ExecContext(ctx, `
+	INSERT INTO outbox (tenant_id, todo_id, event_type, payload, created_at)
+	VALUES(?, ?, ?, ?, ?)
+	`, m.Tenant, m.TodoID, m.Type, string(m.Payload), m.CreatedAt)
+	return err
+}
+
+// Outbox is the reading side of the outbox table. Only the oldest pending row
+// of every todo is eligible, so a todo's events are published in order.
+// SQLite has a single writer, so messages are claimed without holding a
+// transaction while they are published, and only one relay may poll.
+type Outbox struct {
+	db *sql.DB
+}
+
+var _ outbox.Store = (*Outbox)(nil)
+
+func NewOutbox(db *sql.DB) *Outbox {
+	return &Outbox{db: db}
+}
+
+// Process implements outbox.Store.
+func (o *Outbox) Process(ctx context.Context, limit int, fn func(ctx context.Context, msgs []outbox.Message) []int64) (int, error) {
+	rows, err := o.db.QueryContext(ctx, `
+	SELECT o.id, o.tenant_id, o.todo_id, o.event_type, o.payload, o.created_at
+	FROM outbox o
+	WHERE o.delivered_at IS NULL
+	AND NOT EXISTS (
+		SELECT 1 FROM outbox p
+		WHERE p.tenant_id = o.tenant_id AND p.todo_id = o.todo_id
+		AND p.delivered_at IS NULL AND p.id < o.id
+	)
+	ORDER BY o.id
+	LIMIT ?
+	`, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var msgs []outbox.Message
+	for rows.Next() {
+		m := outbox.Message{}
+		var payload string
+		if err := rows.Scan(&m.ID, &m.Tenant, &m.TodoID, &m.Type, &payload, &m.CreatedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		m.Payload = []byte(payload)
+		m.CreatedAt = m.CreatedAt.UTC()
+		msgs = append(msgs, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+
+	done := fn(ctx, msgs)
+	if len(done) == 0 {
+		return 0, nil
+	}
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	for _, id := range done {
+		if _, err := tx.ExecContext(ctx, `
+	UPDATE outbox SET delivered_at = ?
+	WHERE id = ?
+	`, now, id); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(done), nil
+}
@@ -0,0 +1,50 @@
+package storagesqlite
+
+import (
+	"context"
+	"testing"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+	"todo-api/internal/todo/outbox"
+)
+
+func TestOutbox_EventsInOrder(t *testing.T) {
+	s := newStore(t)
+	s.outbox = true
+	ctx := pkg.WithTenant(context.Background(), "acme")
+
+	created, err := s.Create(ctx, todo.Todo{Title: "T"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := s.Remove(ctx, created.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := s.Remove(ctx, created.ID); err == nil {
+		t.Fatalf("second Remove() expected error, got nil")
+	}
+
+	ob := NewOutbox(s.db)
+	var got []string
+	deliver := func(ctx context.Context, msgs []outbox.Message) []int64 {
+		var ids []int64
+		for _, m := range msgs {
+			if m.Tenant != "acme" || m.TodoID != created.ID {
+				t.Fatalf("message = %+v, want tenant acme, todo %d", m, created.ID)
+			}
+			got = append(got, m.Type)
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}
+	// one event per todo and round, the removal waits for the creation
+	for range 3 {
+		if _, err := ob.Process(context.Background(), 10, deliver); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+	}
+
+	if len(got) != 2 || got[0] != todo.EventCreated || got[1] != todo.EventRemoved {
+		t.Fatalf("published = %v, want [%s %s]", got, todo.EventCreated, todo.EventRemoved)
+	}
+}
@@ -0,0 +1,173 @@
+package storagesqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"todo-api/internal/pkg"
+	"todo-api/internal/todo"
+	"todo-api/internal/todo/outbox"
+)
+
+type SQLiteStore struct {
+	db     *sql.DB
+	outbox bool
+}
+
+type Option func(*SQLiteStore)
+
+// WithOutbox makes every mutation insert a domain event into the outbox table
+// in the same transaction.
+func WithOutbox() Option {
+	return func(s *SQLiteStore) {
+		s.outbox = true
+	}
+}
+
+func New(db *sql.DB, opts ...Option) *SQLiteStore {
+	s := &SQLiteStore{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// mutate runs fn in a transaction and, when the outbox is enabled, stores the
+// event fn returns within it.
+func (s *SQLiteStore) mutate(ctx context.Context, fn func(tx *sql.Tx) (outbox.Message, error)) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	msg, err := fn(tx)
+	if err != nil {
+		return err
+	}
+	if s.outbox {
+		if err := insertMessage(ctx, tx, msg); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+var _ todo.Repository = (*SQLiteStore)(nil)
+
+// Ping implements todo.Repository.
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Create implements todo.Repository.
+func (s *SQLiteStore) Create(ctx context.Context, t todo.Todo) (todo.Todo, error) {
+	if strings.TrimSpace(t.Status) == "" {
+		t.Status = todo.StatusPending
+	} else {
+		t.Status = strings.ToLower(t.Status)
+	}
+	now := time.Now().UTC()
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	if t.PublicID == "" {
+		t.PublicID = todo.NewPublicID()
+	}
+
+	tenant := pkg.TenantFrom(ctx)
+	err := s.mutate(ctx, func(tx *sql.Tx) (outbox.Message, error) {
+		err := tx.QueryRowContext(ctx, `
+	INSERT INTO tenant_sequences (tenant_id, last_id)
+	VALUES(?, 1)
+	ON CONFLICT (tenant_id) DO UPDATE SET last_id = last_id + 1
+	RETURNING last_id
+	`, tenant).Scan(&t.ID)
+		if err != nil {
+			return outbox.Message{}, err
+		}
+		_, err = tx.ExecContext(ctx, `
+	INSERT INTO todos (tenant_id, id, public_id, title, description, status, created_at, updated_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+	`, tenant, t.ID, t.PublicID, t.Title, t.Description, t.Status, t.CreatedAt, t.UpdatedAt)
+		if err != nil {
+			return outbox.Message{}, err
+		}
+		return outbox.NewMessage(tenant, t.ID, todo.EventCreated, todo.ToDTO(t))
+	})
+	if err != nil {
+		return todo.Todo{}, err
+	}
+	return t, nil
+}
+
+// Get implements todo.Repository.
+func (s *SQLiteStore) Get(ctx context.Context, id int64) (todo.Todo, error) {
+	if id <= 0 {
+		return todo.Todo{}, todo.ErrNotFound
+	}
+
+	res := todo.Todo{}
+	err := s.db.QueryRowContext(ctx, `
+	SELECT id, public_id, title, description, status, created_at, updated_at
+	FROM todos
+	WHERE tenant_id = ? AND id = ?
+	`, pkg.TenantFrom(ctx), id).Scan(
+		&res.ID,
+		&res.PublicID,
+		&res.Title,
+		&res.Description,
+		&res.Status,
+		&res.CreatedAt,
+		&res.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.Todo{}, todo.ErrNotFound
+		}
+		return todo.Todo{}, err
+	}
+
+	res.CreatedAt = res.CreatedAt.UTC()
+	res.UpdatedAt = res.UpdatedAt.UTC()
+	return res, nil
+}
+
+// Resolve implements todo.Repository.
+func (s *SQLiteStore) Resolve(ctx context.Context, publicID string) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+	SELECT id
+	FROM todos
+	WHERE tenant_id = ? AND public_id = ?
+	`, pkg.TenantFrom(ctx), publicID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, todo.ErrNotFound
+	}
+	return id, err
+}
+
+// Remove implements todo.Repository.
+func (s *SQLiteStore) Remove(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return todo.ErrNotFound
+	}
+
+	tenant := pkg.TenantFrom(ctx)
+	return s.mutate(ctx, func(tx *sql.Tx) (outbox.Message, error) {
+		var publicID string
+		err := tx.QueryRowContext(ctx, `
+	DELETE FROM todos
+	WHERE tenant_id = ? AND id = ?
+	RETURNING public_id
+	`, tenant, id).Scan(&publicID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return outbox.Message{}, todo.ErrNotFound
+		}
+		if err != nil {
+			return outbox.Message{}, err
+		}
+		return outbox.NewMessage(tenant, id, todo.EventRemoved, todo.RemovedPayload{ID: publicID})
+	})
+}
@@ -0,0 +1,215 @@
+package storagesqlite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"todo-api/internal/todo"
+)
+
+func newStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+
+	db, err := Open(filepath.Join(t.TempDir(), "todos.db"), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := Migrate(context.Background(), db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	return New(db)
+}
+
+func TestMigrate_Idempotent(t *testing.T) {
+	s := newStore(t)
+
+	if err := Migrate(context.Background(), s.db); err != nil {
+		t.Fatalf("second Migrate() error = %v", err)
+	}
+
+	var version int
+	s.db.QueryRow(`PRAGMA user_version`).Scan(&version)
+	if version != 4 {
+		t.Fatalf("user_version = %d, want 4", version)
+	}
+
+	var mode string
+	s.db.QueryRow(`PRAGMA journal_mode`).Scan(&mode)
+	if mode != "wal" {
+		t.Fatalf("journal_mode = %q, want wal", mode)
+	}
+}
+
+func TestMigrate_KeepsData(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "todos.db"), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+
+	for v, name := range []string{"001_init.sql", "002_outbox.sql"} {
+		body, _ := migrations.ReadFile("migrations/" + name)
+		if err := apply(ctx, db, v+1, string(body)); err != nil {
+			t.Fatalf("apply(%s) error = %v", name, err)
+		}
+	}
+	now := time.Date(2025, 3, 4, 5, 6, 7, 123456789, time.UTC)
+	for i := 0; i < 3; i++ {
+		db.Exec(`INSERT INTO todos (title, status, created_at, updated_at) VALUES ('T', 'pending', ?, ?)`, now, now)
+	}
+	db.Exec(`DELETE FROM todos WHERE id = 3`)
+
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	s := New(db)
+	old, err := s.Get(ctx, 2)
+	if err != nil {
+		t.Fatalf("Get() existing todo error = %v", err)
+	}
+	if !todo.ValidPublicID(old.PublicID) {
+		t.Fatalf("Get() backfilled public id = %q, want a UUIDv7", old.PublicID)
+	}
+	// the timestamp of the UUIDv7 is created_at
+	if ms, _ := strconv.ParseInt(strings.ReplaceAll(old.PublicID, "-", "")[:12], 16, 64); ms != now.UnixMilli() {
+		t.Fatalf("Get() backfilled public id time = %d, want %d", ms, now.UnixMilli())
+	}
+	var notNull int
+	db.QueryRow(`SELECT "notnull" FROM pragma_table_info('todos') WHERE name = 'public_id'`).Scan(&notNull)
+	if notNull != 1 {
+		t.Fatalf("public_id nullable, want NOT NULL")
+	}
+	if id, err := s.Resolve(ctx, old.PublicID); err != nil || id != 2 {
+		t.Fatalf("Resolve() = %d, %v; want 2", id, err)
+	}
+	out, err := s.Create(ctx, todo.Todo{Title: "T"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if out.ID != 4 {
+		t.Fatalf("Create() id = %d, want 4", out.ID)
+	}
+}
+
+func TestPing_OK(t *testing.T) {
+	s := newStore(t)
+	if err := s.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+}
+
+func TestCreateGet_OK(t *testing.T) {
+	s := newStore(t)
+	ctx := context.Background()
+
+	desc := "D"
+	out, err := s.Create(ctx, todo.Todo{Title: "T", Description: &desc, Status: "   "})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if out.ID <= 0 || out.Status != todo.StatusPending {
+		t.Fatalf("Create() unexpected todo: %v", out)
+	}
+	if out.CreatedAt.IsZero() || !out.CreatedAt.Equal(out.UpdatedAt) {
+		t.Fatalf("Create() timestamps: created_at=%v, updated_at=%v", out.CreatedAt, out.UpdatedAt)
+	}
+
+	got, err := s.Get(ctx, out.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.ID != out.ID || got.Title != "T" || *got.Description != "D" || got.Status != todo.StatusPending {
+		t.Fatalf("Get() unexpected todo: %v", got)
+	}
+	if !got.CreatedAt.Equal(out.CreatedAt) {
+		t.Fatalf("Get() created_at = %v, want %v", got.CreatedAt, out.CreatedAt)
+	}
+}
+
+func TestCreate_StatusLowercased(t *testing.T) {
+	s := newStore(t)
+
+	out, err := s.Create(context.Background(), todo.Todo{Title: "T", Status: "Done"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if out.Status != "done" {
+		t.Fatalf("Create() status = %q, want done", out.Status)
+	}
+}
+
+func TestGet_NotFound(t *testing.T) {
+	s := newStore(t)
+
+	for _, id := range []int64{0, -1, 999} {
+		if _, err := s.Get(context.Background(), id); !errors.Is(err, todo.ErrNotFound) {
+			t.Fatalf("Get(%d) err = %v, want %v", id, err, todo.ErrNotFound)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	s := newStore(t)
+	ctx := context.Background()
+
+	out, _ := s.Create(ctx, todo.Todo{Title: "T"})
+
+	if err := s.Remove(ctx, out.ID+1); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Remove() other err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if err := s.Remove(ctx, out.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := s.Get(ctx, out.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Get() err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if err := s.Remove(ctx, out.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Remove() twice err = %v, want %v", err, todo.ErrNotFound)
+	}
+	if err := s.Remove(ctx, 0); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("Remove(0) err = %v, want %v", err, todo.ErrNotFound)
+	}
+
+	// ids are not reused after a delete
+	next, _ := s.Create(ctx, todo.Todo{Title: "T"})
+	if next.ID <= out.ID {
+		t.Fatalf("Create() id = %d, want > %d", next.ID, out.ID)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	s := newStore(t)
+
+	const threadNum = 50
+	wg := sync.WaitGroup{}
+	errs := make(chan error, threadNum)
+	for i := 0; i < threadNum; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Create(context.Background(), todo.Todo{Title: "T"}); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	var n int
+	s.db.QueryRow(`SELECT COUNT(*) FROM todos`).Scan(&n)
+	if n != threadNum {
+		t.Fatalf("rows = %d, want %d", n, threadNum)
+	}
+}
@@ -0,0 +1,12 @@
+package todo
+
+import "context"
+
+// Transactor runs a unit of work atomically.
+//
+// Repository calls made with the ctx passed to fn take part in the
+// transaction. If fn returns an error everything is rolled back, a nested
+// WithinTx joins the outer transaction.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,25 @@
+-- ids are only unique per tenant, so only a single tenant fits back into
+-- the global id space
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM todos WHERE tenant_id <> 'default')
+        OR EXISTS (SELECT 1 FROM outbox WHERE tenant_id <> 'default') THEN
+        RAISE EXCEPTION 'todos of tenants other than default exist, cannot revert to global ids';
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS outbox_pending_idx;
+ALTER TABLE outbox DROP COLUMN IF EXISTS tenant_id;
+CREATE INDEX IF NOT EXISTS outbox_pending_idx
+    ON outbox (todo_id, id)
+    WHERE delivered_at IS NULL;
+
+-- continue the global sequence where the default tenant's counter stopped
+SELECT setval('todos_id_seq', last_id) FROM tenant_sequences
+WHERE tenant_id = 'default' AND last_id > 0;
+DROP TABLE IF EXISTS tenant_sequences;
+
+ALTER TABLE todos ALTER COLUMN id SET DEFAULT nextval('todos_id_seq');
+ALTER TABLE todos DROP CONSTRAINT IF EXISTS todos_pkey;
+ALTER TABLE todos ADD PRIMARY KEY (id);
+ALTER TABLE todos DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,21 @@
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE todos ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE todos DROP CONSTRAINT IF EXISTS todos_pkey;
+ALTER TABLE todos ADD PRIMARY KEY (tenant_id, id);
+ALTER TABLE todos ALTER COLUMN id DROP DEFAULT;
+
+-- ids are counted per tenant, starting where the global sequence stopped
+CREATE TABLE IF NOT EXISTS tenant_sequences (
+    tenant_id TEXT PRIMARY KEY,
+    last_id BIGINT NOT NULL
+);
+INSERT INTO tenant_sequences (tenant_id, last_id)
+SELECT 'default', last_value FROM todos_id_seq WHERE is_called
+ON CONFLICT (tenant_id) DO NOTHING;
+
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE outbox ALTER COLUMN tenant_id DROP DEFAULT;
+DROP INDEX IF EXISTS outbox_pending_idx;
+CREATE INDEX IF NOT EXISTS outbox_pending_idx
+    ON outbox (tenant_id, todo_id, id)
+    WHERE delivered_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS todos_public_id_idx;
+ALTER TABLE todos DROP COLUMN IF EXISTS public_id;
@@ -0,0 +1,15 @@
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS public_id UUID;
+
+-- backfill with UUIDv7 built from created_at: the version 4 bits of a random
+-- uuid are turned into 7 and its first 48 bits replaced by the timestamp
+UPDATE todos SET public_id = encode(
+    set_bit(set_bit(
+        overlay(uuid_send(gen_random_uuid())
+            placing substring(int8send((extract(epoch FROM created_at) * 1000)::bigint) FROM 3)
+            FROM 1 FOR 6),
+    52, 1), 53, 1),
+    'hex')::uuid
+WHERE public_id IS NULL;
+
+ALTER TABLE todos ALTER COLUMN public_id SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS todos_public_id_idx ON todos (tenant_id, public_id);
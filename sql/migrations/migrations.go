@@ -0,0 +1,10 @@
+// Package migrations holds the Postgres schema migrations.
+//
+// NNN_name.sql upgrades the schema to version NNN, the optional
+// NNN_name.down.sql reverts it.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS